@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// defaultRetryPeriod is the default wait period before reconciling a
+	// cluster with components that aren't ready.
+	defaultRetryPeriod = 5 * time.Second
+
+	// defaultMaxBackoff is the default maximum delay of the failed reconciles
+	// backoff, same as the controller-runtime default.
+	defaultMaxBackoff = 1000 * time.Second
+
+	// defaultChannel is the default manifests channel.
+	defaultChannel = "stable"
+)
+
+// GetRetryPeriod returns the retry period of the clusters with components
+// that aren't ready.
+func (c OperatorConfig) GetRetryPeriod() time.Duration {
+	if c.RetryPeriod != nil && c.RetryPeriod.Duration > 0 {
+		return c.RetryPeriod.Duration
+	}
+	return defaultRetryPeriod
+}
+
+// GetMaxBackoff returns the maximum delay of the failed reconciles backoff.
+func (c OperatorConfig) GetMaxBackoff() time.Duration {
+	if c.MaxBackoff != nil && c.MaxBackoff.Duration > 0 {
+		return c.MaxBackoff.Duration
+	}
+	return defaultMaxBackoff
+}
+
+// GetResyncInterval returns the periodic resync interval of the clusters.
+// Zero disables the resync.
+func (c OperatorConfig) GetResyncInterval() time.Duration {
+	if c.ResyncInterval != nil && c.ResyncInterval.Duration > 0 {
+		return c.ResyncInterval.Duration
+	}
+	return 0
+}
+
+// GetExecutionStrategy returns the execution strategy of the operands.
+func (c OperatorConfig) GetExecutionStrategy() ExecutionStrategy {
+	if c.ExecutionStrategy != "" {
+		return c.ExecutionStrategy
+	}
+	return ExecutionStrategyParallel
+}
+
+// GetChannel returns the manifests channel.
+func (c OperatorConfig) GetChannel() string {
+	if c.Channel != "" {
+		return c.Channel
+	}
+	return defaultChannel
+}
+
+// Validate checks the typed options of the operator configuration. The
+// feature gates and operand names are validated by their consumers.
+func (c OperatorConfig) Validate() error {
+	switch c.GetExecutionStrategy() {
+	case ExecutionStrategyParallel, ExecutionStrategySerial:
+	default:
+		return fmt.Errorf("invalid execution strategy %q, must be one of %s or %s", c.ExecutionStrategy, ExecutionStrategyParallel, ExecutionStrategySerial)
+	}
+
+	// The channel is a file in the channels directory.
+	if errs := validation.IsDNS1123Subdomain(c.GetChannel()); len(errs) > 0 {
+		return fmt.Errorf("invalid channel %q: %s", c.Channel, strings.Join(errs, ", "))
+	}
+
+	for _, ns := range c.WatchNamespaces {
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return fmt.Errorf("invalid watch namespace %q: %s", ns, strings.Join(errs, ", "))
+		}
+	}
+
+	// The registry is a host with an optional port and path, e.g.
+	// registry.example.com:5000/mirror.
+	if c.ImageRegistry != "" {
+		if strings.Contains(c.ImageRegistry, "://") || strings.HasSuffix(c.ImageRegistry, "/") {
+			return fmt.Errorf("invalid image registry %q, must be a host with an optional port and path, without scheme or trailing slash", c.ImageRegistry)
+		}
+	}
+
+	for _, d := range []struct {
+		name     string
+		duration *metav1.Duration
+	}{
+		{"retryPeriod", c.RetryPeriod},
+		{"maxBackoff", c.MaxBackoff},
+		{"resyncInterval", c.ResyncInterval},
+	} {
+		if d.duration != nil && d.duration.Duration < 0 {
+			return fmt.Errorf("invalid %s %s, must not be negative", d.name, d.duration.Duration)
+		}
+	}
+
+	return nil
+}
@@ -30,6 +30,130 @@ type OperatorConfig struct {
 	// ValidatingWebhookConfigRef is the reference of the validating webhook
 	// configuration.
 	ValidatingWebhookConfigRef string `json:"validatingWebhookConfigRef,omitempty"`
+
+	// MutatingWebhookConfigRef is the reference of the mutating webhook
+	// configuration.
+	MutatingWebhookConfigRef string `json:"mutatingWebhookConfigRef,omitempty"`
+
+	// Tracing is the tracing exporter configuration.
+	Tracing TracingConfig `json:"tracing,omitempty"`
+
+	// RetryPeriod is the wait period before reconciling a cluster again when
+	// its components aren't ready. Defaults to 5s.
+	RetryPeriod *metav1.Duration `json:"retryPeriod,omitempty"`
+
+	// MaxBackoff is the maximum delay of the exponential backoff of the
+	// failed reconciles. Defaults to 1000s.
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
+
+	// ResyncInterval is the interval at which the clusters are reconciled
+	// without any change, refreshing their status. Disabled when unset.
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+
+	// OperandRequeueStrategies overrides the requeue strategies of the
+	// operands, keyed by operand name, e.g. node, csi or api-manager.
+	// OnError requeues when the operand fails, Always also requeues after
+	// the operand applies a change. Defaults to OnError. The node operand
+	// fails until the control plane is configured.
+	OperandRequeueStrategies map[string]RequeueStrategy `json:"operandRequeueStrategies,omitempty"`
+
+	// FeatureGates enables or disables the operator features, keyed by
+	// feature name.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// ExecutionStrategy is the execution strategy of the operands. Parallel
+	// runs the independent operands concurrently, Serial runs all the
+	// operands one at a time. Defaults to Parallel.
+	ExecutionStrategy ExecutionStrategy `json:"executionStrategy,omitempty"`
+
+	// Channel is the name of the manifests channel used to create the
+	// operands. Defaults to stable.
+	Channel string `json:"channel,omitempty"`
+
+	// WatchNamespaces restricts the reconciled StorageOSClusters to the
+	// given namespaces. The StorageOSClusters created in other namespaces
+	// are rejected. All the namespaces are watched when empty.
+	WatchNamespaces []string `json:"watchNamespaces,omitempty"`
+
+	// ImageRegistry overrides the registry of the default images of the
+	// operator, set by the RELATED_IMAGE_* environment variables. The images
+	// set in the StorageOSCluster spec aren't changed.
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+}
+
+// ExecutionStrategy is the execution strategy of the operands.
+// +kubebuilder:validation:Enum=Parallel;Serial
+type ExecutionStrategy string
+
+const (
+	// ExecutionStrategyParallel runs the independent operands concurrently.
+	ExecutionStrategyParallel ExecutionStrategy = "Parallel"
+
+	// ExecutionStrategySerial runs the operands one at a time.
+	ExecutionStrategySerial ExecutionStrategy = "Serial"
+)
+
+// RequeueStrategy is the requeue strategy of an operand.
+// +kubebuilder:validation:Enum=OnError;Always
+type RequeueStrategy string
+
+const (
+	// RequeueOnError requeues the reconcile when the operand fails.
+	RequeueOnError RequeueStrategy = "OnError"
+
+	// RequeueAlways requeues the reconcile when the operand fails or applies
+	// a change.
+	RequeueAlways RequeueStrategy = "Always"
+)
+
+// TracingExporter is the exporter of the operator traces.
+// +kubebuilder:validation:Enum=none;otlp-grpc;otlp-http;jaeger;stdout
+type TracingExporter string
+
+const (
+	// TracingExporterNone disables tracing.
+	TracingExporterNone TracingExporter = "none"
+
+	// TracingExporterOTLPGRPC exports the traces to an OTLP collector over
+	// gRPC.
+	TracingExporterOTLPGRPC TracingExporter = "otlp-grpc"
+
+	// TracingExporterOTLPHTTP exports the traces to an OTLP collector over
+	// HTTP.
+	TracingExporterOTLPHTTP TracingExporter = "otlp-http"
+
+	// TracingExporterJaeger exports the traces to a Jaeger collector.
+	TracingExporterJaeger TracingExporter = "jaeger"
+
+	// TracingExporterStdout writes the traces to the standard output.
+	TracingExporterStdout TracingExporter = "stdout"
+)
+
+// TracingConfig contains the tracing exporter configuration.
+type TracingConfig struct {
+	// Exporter is the tracing exporter. One of none, otlp-grpc, otlp-http,
+	// jaeger or stdout. When unset, the Jaeger exporter is configured with
+	// the OTEL_EXPORTER_JAEGER_* environment variables and is disabled
+	// unless DISABLE_TRACING is set to false.
+	Exporter TracingExporter `json:"exporter,omitempty"`
+
+	// Endpoint is the address of the trace collector. The exporter default
+	// is used when unset: localhost:4317 for the OTLP exporters and the
+	// OTEL_EXPORTER_JAEGER_ENDPOINT environment variable or
+	// http://localhost:14250 for jaeger.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Insecure disables the transport security of the OTLP exporters.
+	Insecure bool `json:"insecure,omitempty"`
+
+	// SamplingRatio is the ratio of the sampled traces, between 0 and 1,
+	// e.g. "0.1". Child spans follow the sampling decision of their parent.
+	// Defaults to "1", sampling all the traces.
+	SamplingRatio string `json:"samplingRatio,omitempty"`
+
+	// ResourceAttributes are added to the resource of the exported traces,
+	// in addition to the service name.
+	ResourceAttributes map[string]string `json:"resourceAttributes,omitempty"`
 }
 
 func init() {
@@ -2,15 +2,44 @@ package v1
 
 import (
 	"fmt"
+	"path"
+	"strconv"
 )
 
 const (
-	// defaultPluginRegistrationPath is the default kubelet plugin registration
-	// directory path.
-	defaultPluginRegistrationPath = "/var/lib/kubelet/plugins_registry"
+	// defaultKubeletDir is the default kubelet root directory path.
+	defaultKubeletDir = "/var/lib/kubelet"
 
-	// defaultCSIEndpoint is the default path to the storageos CSI socket.
-	defaultCSIEndpoint = "/storageos/csi.sock"
+	// defaultDeviceDir is the default device directory path.
+	defaultDeviceDir = "/dev"
+
+	// Default directory names in the kubelet root and plugin registration
+	// directories.
+	defaultRegistrationDirName    = "plugins_registry"
+	defaultRegistrarSocketDirName = "device-plugins"
+	defaultPluginDirName          = "storageos"
+
+	// csiSocketName is the name of the storageos CSI socket.
+	csiSocketName = "csi.sock"
+
+	// Default CSI version, driver registration mode and deployment strategy.
+	defaultCSIVersion                = "v1"
+	defaultCSIDriverRegistrationMode = "NodeRegistrar"
+	defaultCSIDeploymentStrategy     = "RollingUpdate"
+
+	// defaultVolumeSnapshotClassName is the default name of the
+	// VolumeSnapshotClass.
+	defaultVolumeSnapshotClassName = "storageos-snapshotclass"
+
+	// defaultSharedFilesystemStorageClassName is the default name of the
+	// ReadWriteMany StorageClass.
+	defaultSharedFilesystemStorageClassName = "storageos-rwx"
+
+	// defaultServiceName is the default name of the storageos service.
+	defaultServiceName = "storageos"
+
+	// defaultServicePort is the default port of the storageos service.
+	defaultServicePort = 5705
 
 	// Log levels.
 	debugLogLevel = "debug"
@@ -22,7 +51,120 @@ func (s *StorageOSCluster) GetCSIEndpoint() string {
 	if s.Spec.CSI.Endpoint != "" {
 		return s.Spec.CSI.Endpoint
 	}
-	return fmt.Sprintf("%s%s%s", "unix://", defaultPluginRegistrationPath, defaultCSIEndpoint)
+	return fmt.Sprintf("%s%s", "unix://", path.Join(s.GetCSIPluginDir(), csiSocketName))
+}
+
+// GetCSIVersion returns the CSI version of the cluster.
+func (s *StorageOSCluster) GetCSIVersion() string {
+	if s.Spec.CSI.Version != "" {
+		return s.Spec.CSI.Version
+	}
+	return defaultCSIVersion
+}
+
+// GetKubeletDir returns the kubelet root directory of the cluster.
+func (s *StorageOSCluster) GetKubeletDir() string {
+	if s.Spec.CSI.KubeletDir != "" {
+		return s.Spec.CSI.KubeletDir
+	}
+	return defaultKubeletDir
+}
+
+// GetCSIRegistrationDir returns the kubelet plugin registration directory of
+// the cluster.
+func (s *StorageOSCluster) GetCSIRegistrationDir() string {
+	if s.Spec.CSI.RegistrationDir != "" {
+		return s.Spec.CSI.RegistrationDir
+	}
+	return path.Join(s.GetKubeletDir(), defaultRegistrationDirName)
+}
+
+// GetCSIRegistrarSocketDir returns the kubelet device plugins directory of
+// the cluster.
+func (s *StorageOSCluster) GetCSIRegistrarSocketDir() string {
+	if s.Spec.CSI.RegistrarSocketDir != "" {
+		return s.Spec.CSI.RegistrarSocketDir
+	}
+	return path.Join(s.GetKubeletDir(), defaultRegistrarSocketDirName)
+}
+
+// GetCSIPluginDir returns the storageos CSI plugin directory of the cluster.
+func (s *StorageOSCluster) GetCSIPluginDir() string {
+	if s.Spec.CSI.PluginDir != "" {
+		return s.Spec.CSI.PluginDir
+	}
+	return path.Join(s.GetCSIRegistrationDir(), defaultPluginDirName)
+}
+
+// GetCSIDeviceDir returns the device directory of the cluster.
+func (s *StorageOSCluster) GetCSIDeviceDir() string {
+	if s.Spec.CSI.DeviceDir != "" {
+		return s.Spec.CSI.DeviceDir
+	}
+	return defaultDeviceDir
+}
+
+// GetCSIKubeletRegistrationPath returns the CSI socket path registered with
+// the kubelet.
+func (s *StorageOSCluster) GetCSIKubeletRegistrationPath() string {
+	if s.Spec.CSI.KubeletRegistrationPath != "" {
+		return s.Spec.CSI.KubeletRegistrationPath
+	}
+	return path.Join(s.GetCSIPluginDir(), csiSocketName)
+}
+
+// GetCSIDriverRegistrationMode returns the CSI driver registration mode of the
+// cluster.
+func (s *StorageOSCluster) GetCSIDriverRegistrationMode() string {
+	if s.Spec.CSI.DriverRegistrationMode != "" {
+		return s.Spec.CSI.DriverRegistrationMode
+	}
+	return defaultCSIDriverRegistrationMode
+}
+
+// GetCSIDriverRequiresAttachment returns if the CSI driver requires the attach
+// operation. An invalid value results in an error.
+func (s *StorageOSCluster) GetCSIDriverRequiresAttachment() (bool, error) {
+	if s.Spec.CSI.DriverRequiresAttachment == "" {
+		return true, nil
+	}
+	return strconv.ParseBool(s.Spec.CSI.DriverRequiresAttachment)
+}
+
+// GetCSIDeploymentStrategy returns the update strategy of the CSI helper
+// deployment.
+func (s *StorageOSCluster) GetCSIDeploymentStrategy() string {
+	if s.Spec.CSI.DeploymentStrategy != "" {
+		return s.Spec.CSI.DeploymentStrategy
+	}
+	return defaultCSIDeploymentStrategy
+}
+
+// GetVolumeSnapshotClassName returns the name of the VolumeSnapshotClass of
+// the cluster.
+func (s *StorageOSCluster) GetVolumeSnapshotClassName() string {
+	if s.Spec.Snapshot.VolumeSnapshotClassName != "" {
+		return s.Spec.Snapshot.VolumeSnapshotClassName
+	}
+	return defaultVolumeSnapshotClassName
+}
+
+// GetVolumeSnapshotDeletionPolicy returns the deletion policy of the
+// VolumeSnapshotClass of the cluster.
+func (s *StorageOSCluster) GetVolumeSnapshotDeletionPolicy() VolumeSnapshotDeletionPolicy {
+	if s.Spec.Snapshot.DeletionPolicy != "" {
+		return s.Spec.Snapshot.DeletionPolicy
+	}
+	return VolumeSnapshotDeletionPolicyDelete
+}
+
+// GetSharedFilesystemStorageClassName returns the name of the ReadWriteMany
+// StorageClass of the cluster.
+func (s *StorageOSCluster) GetSharedFilesystemStorageClassName() string {
+	if s.Spec.SharedFilesystem.StorageClassName != "" {
+		return s.Spec.SharedFilesystem.StorageClassName
+	}
+	return defaultSharedFilesystemStorageClassName
 }
 
 // GetSharedDir returns the shared directory of the cluster.
@@ -40,3 +182,19 @@ func (s *StorageOSCluster) GetLogLevel() string {
 	}
 	return infoLogLevel
 }
+
+// GetServiceName returns the name of the storageos service of the cluster.
+func (s *StorageOSCluster) GetServiceName() string {
+	if s.Spec.Service.Name != "" {
+		return s.Spec.Service.Name
+	}
+	return defaultServiceName
+}
+
+// GetServicePort returns the port of the storageos service of the cluster.
+func (s *StorageOSCluster) GetServicePort() int {
+	if s.Spec.Service.InternalPort != 0 {
+		return s.Spec.Service.InternalPort
+	}
+	return defaultServicePort
+}
@@ -19,6 +19,20 @@ type StorageOSClusterSpec struct {
 	// CSI defines the configurations for CSI.
 	CSI StorageOSClusterCSI `json:"csi,omitempty"`
 
+	// Snapshot defines the configurations for CSI volume snapshots.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Snapshot StorageOSClusterSnapshot `json:"snapshot,omitempty"`
+
+	// SharedFilesystem defines the configurations for ReadWriteMany shared
+	// filesystem volumes.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	SharedFilesystem StorageOSClusterSharedFilesystem `json:"sharedFilesystem,omitempty"`
+
+	// Monitoring defines the configurations for Prometheus Operator based
+	// monitoring.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Monitoring StorageOSClusterMonitoring `json:"monitoring,omitempty"`
+
 	// Namespace is the kubernetes Namespace where storageos resources are
 	// provisioned.
 	Namespace string `json:"namespace,omitempty"`
@@ -73,10 +87,82 @@ type StorageOSClusterSpec struct {
 	//+operator-sdk:csv:customresourcedefinitions:type=spec
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 
+	// TolerationsMode defines how Tolerations are combined with the default
+	// tolerations of the storageos pods. "Append" (default) adds Tolerations
+	// to the defaults, "Replace" uses only Tolerations, and "Subtract" removes
+	// the default tolerations matching the key (and effect, if set) of any of
+	// Tolerations. The resulting tolerations are applied to all the storageos
+	// pods.
+	// NOTE: Kubernetes adds some tolerations, like node.kubernetes.io/unschedulable,
+	// to DaemonSet pods automatically. These can't be removed.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	TolerationsMode TolerationsMode `json:"tolerationsMode,omitempty"`
+
+	// PodAntiAffinity is to set the pod anti-affinity of the storageos
+	// control Deployments (api-manager and csi-helper). If not set, a
+	// preferred anti-affinity is used to spread the replicas across nodes.
+	// Terms without a labelSelector are scoped to the pods of the Deployment
+	// they're applied to.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	PodAntiAffinity *corev1.PodAntiAffinity `json:"podAntiAffinity,omitempty"`
+
+	// TopologySpreadConstraints is to set the topology spread constraints of
+	// the storageos control Deployments (api-manager and csi-helper). If not
+	// set, the replicas are spread across zones when possible. Constraints
+	// without a labelSelector are scoped to the pods of the Deployment they're
+	// applied to.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
 	// Resources is to set the resource requirements of the storageos containers.
 	//+operator-sdk:csv:customresourcedefinitions:type=spec
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 
+	// NodeContainers is a list of additional configurations for the
+	// containers of the storageos node pods, like extra environment
+	// variables, args and volume mounts. Environment variables, args and
+	// volumes managed by the operator can't be overridden.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	NodeContainers []NodeContainer `json:"nodeContainers,omitempty"`
+
+	// NodeExtraVolumes is a list of additional volumes for the storageos node
+	// pods. These can be mounted in the node containers using the
+	// extraVolumeMounts of NodeContainers.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	NodeExtraVolumes []corev1.Volume `json:"nodeExtraVolumes,omitempty"`
+
+	// NodeConfigRefName is the name of a ConfigMap, in the cluster namespace,
+	// containing additional storageos node configurations, e.g. LOG_FORMAT.
+	// The keys of the ConfigMap are merged into the default node
+	// configuration and take precedence over the defaults. Keys managed by
+	// the operator through other spec fields, like ETCD_ENDPOINTS or
+	// LOG_LEVEL, can't be set. Changes to the ConfigMap are applied to the
+	// node configuration and take effect when the node pods restart.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	NodeConfigRefName string `json:"nodeConfigRefName,omitempty"`
+
+	// CommonLabels are added to all the resources created for the cluster,
+	// including the pod templates. They aren't added to the label selectors.
+	// The "app" and "app.kubernetes.io/component" labels are reserved.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+
+	// CommonAnnotations are added to all the resources created for the
+	// cluster, including the pod templates.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+
+	// PodLabels are added to the pod templates of all the storageos
+	// workloads. They aren't added to the label selectors. The "app" and
+	// "app.kubernetes.io/component" labels are reserved.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+
+	// PodAnnotations are added to the pod templates of all the storageos
+	// workloads.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
 	// Disable Pod Fencing.  With StatefulSets, Pods are only re-scheduled if
 	// the Pod has been marked as killed.  In practice this means that failover
 	// of a StatefulSet pod is a manual operation.
@@ -133,17 +219,129 @@ type StorageOSClusterSpec struct {
 	// K8sDistro is the name of the Kubernetes distribution where the operator
 	// is being deployed.  It should be in the format: `name[-1.0]`, where the
 	// version is optional and should only be appended if known.  Suitable names
-	// include: `openshift`, `rancher`, `aks`, `gke`, `eks`, or the deployment
-	// method if using upstream directly, e.g `minishift` or `kubeadm`.
+	// include: `openshift`, `rancher`, `aks`, `gke`, `eks`, `k3s`, `microk8s`,
+	// or the deployment method if using upstream directly, e.g `minishift` or
+	// `kubeadm`.
 	//
 	// Setting k8sDistro is optional, and will be used to simplify cluster
-	// configuration by setting appropriate defaults for the distribution.  The
-	// distribution information will also be included in the product telemetry
-	// (if enabled), to help focus development efforts.
+	// configuration by setting appropriate defaults for the distribution, such
+	// as the kubelet directory and the pod priority class.  Explicitly set
+	// fields take precedence over the distribution defaults.  When not set,
+	// the operator attempts to detect the distribution.  The distribution
+	// information will also be included in the product telemetry (if enabled),
+	// to help focus development efforts.
 	K8sDistro string `json:"k8sDistro,omitempty"`
 
 	// Disable StorageOS scheduler extender.
 	DisableScheduler bool `json:"disableScheduler,omitempty"`
+
+	// Scheduler is the configuration of the storageos scheduler. It can be
+	// used to tune the default scheduler plugins of the storageos-scheduler
+	// profile, or to schedule without the storageos scheduler extender.
+	//+operator-sdk:csv:customresourcedefinitions:type=spec
+	Scheduler StorageOSClusterScheduler `json:"scheduler,omitempty"`
+}
+
+// TolerationsMode is the mode in which the tolerations in the cluster spec are
+// combined with the default tolerations.
+// +kubebuilder:validation:Enum=Append;Replace;Subtract
+type TolerationsMode string
+
+const (
+	// TolerationsModeAppend appends the tolerations to the default
+	// tolerations.
+	TolerationsModeAppend TolerationsMode = "Append"
+
+	// TolerationsModeReplace replaces the default tolerations with the
+	// tolerations.
+	TolerationsModeReplace TolerationsMode = "Replace"
+
+	// TolerationsModeSubtract removes the tolerations from the default
+	// tolerations.
+	TolerationsModeSubtract TolerationsMode = "Subtract"
+)
+
+// SchedulerMode is the mode in which the storageos scheduler places pods.
+// +kubebuilder:validation:Enum=Extender;Profile
+type SchedulerMode string
+
+const (
+	// SchedulerModeExtender uses the storageos scheduler extender to filter
+	// and prioritize the nodes, in addition to the profile plugins.
+	SchedulerModeExtender SchedulerMode = "Extender"
+
+	// SchedulerModeProfile uses only the profile plugins to filter and score
+	// the nodes, without the storageos scheduler extender.
+	SchedulerModeProfile SchedulerMode = "Profile"
+)
+
+// StorageOSClusterScheduler contains the storageos scheduler configurations.
+type StorageOSClusterScheduler struct {
+	// Mode is the mode in which the storageos scheduler places pods.
+	// Extender (default) calls the storageos scheduler extender. Profile
+	// removes the extender and relies only on the profile plugins.
+	Mode SchedulerMode `json:"mode,omitempty"`
+
+	// PercentageOfNodesToScore is the percentage of all nodes that, once
+	// found feasible for running a pod, the scheduler stops its search for
+	// more feasible nodes. 0 uses the kube-scheduler default.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	PercentageOfNodesToScore *int32 `json:"percentageOfNodesToScore,omitempty"`
+
+	// Plugins is a list of plugin configurations of the scheduling framework
+	// extension points in the storageos-scheduler profile.
+	Plugins []SchedulerPluginSet `json:"plugins,omitempty"`
+}
+
+// SchedulerPluginSet contains the enabled and disabled plugins of a
+// scheduling framework extension point.
+type SchedulerPluginSet struct {
+	// ExtensionPoint is the scheduling framework extension point. multiPoint
+	// requires kubernetes v1.23 or later.
+	// +kubebuilder:validation:Enum=queueSort;preFilter;filter;postFilter;preScore;score;reserve;permit;preBind;bind;postBind;multiPoint
+	ExtensionPoint string `json:"extensionPoint"`
+
+	// Enabled is a list of plugins to enable in addition to the default
+	// plugins.
+	Enabled []SchedulerPlugin `json:"enabled,omitempty"`
+
+	// Disabled is a list of default plugins to disable. "*" disables all
+	// the default plugins of the extension point.
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+// SchedulerPlugin is a scheduling framework plugin.
+type SchedulerPlugin struct {
+	// Name is the name of the plugin.
+	Name string `json:"name"`
+
+	// Weight is the weight of the plugin. Only applies to score and
+	// multiPoint plugins.
+	// +kubebuilder:validation:Minimum=1
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// NodeContainer defines additional configurations for a container of the
+// storageos node pods.
+type NodeContainer struct {
+	// Name is the name of the container. One of storageos, storageos-init,
+	// csi-driver-registrar or csi-liveness-probe.
+	// +kubebuilder:validation:Enum=storageos;storageos-init;csi-driver-registrar;csi-liveness-probe
+	Name string `json:"name"`
+
+	// ExtraEnv is a list of additional environment variables for the
+	// container.
+	ExtraEnv []corev1.EnvVar `json:"extraEnv,omitempty"`
+
+	// ExtraArgs is a list of additional args for the container. These are
+	// appended to the default args.
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+
+	// ExtraVolumeMounts is a list of additional volume mounts for the
+	// container. The volumes must be defined in NodeExtraVolumes. subPath and
+	// subPathExpr are not supported.
+	ExtraVolumeMounts []corev1.VolumeMount `json:"extraVolumeMounts,omitempty"`
 }
 
 // ContainerImages contains image names of all the containers used by the operator.
@@ -155,6 +353,7 @@ type ContainerImages struct {
 	CSIExternalProvisionerContainer    string `json:"csiExternalProvisionerContainer,omitempty"`
 	CSIExternalAttacherContainer       string `json:"csiExternalAttacherContainer,omitempty"`
 	CSIExternalResizerContainer        string `json:"csiExternalResizerContainer,omitempty"`
+	CSIExternalSnapshotterContainer    string `json:"csiExternalSnapshotterContainer,omitempty"`
 	CSILivenessProbeContainer          string `json:"csiLivenessProbeContainer,omitempty"`
 	HyperkubeContainer                 string `json:"hyperkubeContainer,omitempty"`
 	KubeSchedulerContainer             string `json:"kubeSchedulerContainer,omitempty"`
@@ -164,22 +363,119 @@ type ContainerImages struct {
 
 // StorageOSClusterCSI contains CSI configurations.
 type StorageOSClusterCSI struct {
-	Enable                       bool   `json:"enable,omitempty"`
-	Version                      string `json:"version,omitempty"`
-	Endpoint                     string `json:"endpoint,omitempty"`
-	EnableProvisionCreds         bool   `json:"enableProvisionCreds,omitempty"`
-	EnableControllerPublishCreds bool   `json:"enableControllerPublishCreds,omitempty"`
-	EnableNodePublishCreds       bool   `json:"enableNodePublishCreds,omitempty"`
-	EnableControllerExpandCreds  bool   `json:"enableControllerExpandCreds,omitempty"`
-	RegistrarSocketDir           string `json:"registrarSocketDir,omitempty"`
-	KubeletDir                   string `json:"kubeletDir,omitempty"`
-	PluginDir                    string `json:"pluginDir,omitempty"`
-	DeviceDir                    string `json:"deviceDir,omitempty"`
-	RegistrationDir              string `json:"registrationDir,omitempty"`
-	KubeletRegistrationPath      string `json:"kubeletRegistrationPath,omitempty"`
-	DriverRegistrationMode       string `json:"driverRegisterationMode,omitempty"`
-	DriverRequiresAttachment     string `json:"driverRequiresAttachment,omitempty"`
-	DeploymentStrategy           string `json:"deploymentStrategy,omitempty"`
+	Enable bool `json:"enable,omitempty"`
+
+	// Version is the CSI version. Only v1 is supported.
+	Version string `json:"version,omitempty"`
+
+	// Endpoint is the CSI endpoint of the storageos node container. Defaults
+	// to the csi.sock socket in PluginDir.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// EnableProvisionCreds enables the provisioner secret parameters in the
+	// StorageClass.
+	EnableProvisionCreds bool `json:"enableProvisionCreds,omitempty"`
+
+	// EnableControllerPublishCreds enables the controller publish secret
+	// parameters in the StorageClass.
+	EnableControllerPublishCreds bool `json:"enableControllerPublishCreds,omitempty"`
+
+	// EnableNodePublishCreds enables the node publish secret parameters in
+	// the StorageClass.
+	EnableNodePublishCreds bool `json:"enableNodePublishCreds,omitempty"`
+
+	// EnableControllerExpandCreds enables the controller expand secret
+	// parameters in the StorageClass.
+	EnableControllerExpandCreds bool `json:"enableControllerExpandCreds,omitempty"`
+
+	// RegistrarSocketDir is the host path of the kubelet device plugins
+	// directory. Defaults to device-plugins in KubeletDir.
+	RegistrarSocketDir string `json:"registrarSocketDir,omitempty"`
+
+	// KubeletDir is the host path of the kubelet root directory. Defaults to
+	// /var/lib/kubelet.
+	KubeletDir string `json:"kubeletDir,omitempty"`
+
+	// PluginDir is the host path of the storageos CSI plugin directory.
+	// Defaults to storageos in RegistrationDir.
+	PluginDir string `json:"pluginDir,omitempty"`
+
+	// DeviceDir is the host path of the device directory. Defaults to /dev.
+	DeviceDir string `json:"deviceDir,omitempty"`
+
+	// RegistrationDir is the host path of the kubelet plugin registration
+	// directory. Defaults to plugins_registry in KubeletDir.
+	RegistrationDir string `json:"registrationDir,omitempty"`
+
+	// KubeletRegistrationPath is the host path of the CSI socket, registered
+	// with the kubelet. Defaults to the csi.sock socket in PluginDir.
+	KubeletRegistrationPath string `json:"kubeletRegistrationPath,omitempty"`
+
+	// DriverRegistrationMode is the CSI driver registration mode. Only
+	// NodeRegistrar, using the node driver registrar, is supported.
+	DriverRegistrationMode string `json:"driverRegisterationMode,omitempty"`
+
+	// DriverRequiresAttachment is set to "false" if the CSI driver doesn't
+	// require the attach operation, skipping the external attacher. It sets
+	// attachRequired of the CSIDriver, which is recreated on change. Defaults
+	// to "true".
+	DriverRequiresAttachment string `json:"driverRequiresAttachment,omitempty"`
+
+	// DeploymentStrategy is the update strategy of the CSI helper
+	// deployment. One of RollingUpdate (default) or Recreate.
+	DeploymentStrategy string `json:"deploymentStrategy,omitempty"`
+}
+
+// VolumeSnapshotDeletionPolicy is the deletion policy of a
+// VolumeSnapshotClass.
+// +kubebuilder:validation:Enum=Delete;Retain
+type VolumeSnapshotDeletionPolicy string
+
+const (
+	// VolumeSnapshotDeletionPolicyDelete deletes the storage snapshot with
+	// its VolumeSnapshotContent.
+	VolumeSnapshotDeletionPolicyDelete VolumeSnapshotDeletionPolicy = "Delete"
+
+	// VolumeSnapshotDeletionPolicyRetain retains the storage snapshot when
+	// its VolumeSnapshotContent is deleted.
+	VolumeSnapshotDeletionPolicyRetain VolumeSnapshotDeletionPolicy = "Retain"
+)
+
+// StorageOSClusterSnapshot contains CSI volume snapshot configurations.
+type StorageOSClusterSnapshot struct {
+	// Enable adds the CSI external snapshotter to the CSI helper and creates
+	// a VolumeSnapshotClass. The snapshot.storage.k8s.io CRDs must be
+	// installed in the cluster.
+	Enable bool `json:"enable,omitempty"`
+
+	// VolumeSnapshotClassName is the name of the VolumeSnapshotClass.
+	// Defaults to storageos-snapshotclass.
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+
+	// DeletionPolicy is the deletion policy of the VolumeSnapshotClass. One
+	// of Delete (default) or Retain.
+	DeletionPolicy VolumeSnapshotDeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// StorageOSClusterSharedFilesystem contains ReadWriteMany shared filesystem
+// configurations.
+type StorageOSClusterSharedFilesystem struct {
+	// Enable enables ReadWriteMany volumes, exported over NFS by the
+	// NFSContainer image. A dedicated ReadWriteMany StorageClass is created.
+	Enable bool `json:"enable,omitempty"`
+
+	// StorageClassName is the name of the ReadWriteMany StorageClass.
+	// Defaults to storageos-rwx.
+	StorageClassName string `json:"storageClassName,omitempty"`
+}
+
+// StorageOSClusterMonitoring contains Prometheus Operator monitoring
+// configurations.
+type StorageOSClusterMonitoring struct {
+	// Enable creates ServiceMonitors for the StorageOS components and a
+	// PrometheusRule with the default alerts. The monitoring.coreos.com CRDs
+	// must be installed in the cluster.
+	Enable bool `json:"enable,omitempty"`
 }
 
 // StorageOSClusterService contains Service configurations.
@@ -205,12 +501,38 @@ type StorageOSClusterKVBackend struct {
 	Backend string `json:"backend,omitempty"`
 }
 
+// Phases of a StorageOS cluster.
+const (
+	// ClusterPhasePending is the phase of a cluster with no ready components.
+	ClusterPhasePending = "Pending"
+
+	// ClusterPhaseCreating is the phase of a cluster with some ready
+	// components that hasn't been running yet.
+	ClusterPhaseCreating = "Creating"
+
+	// ClusterPhaseRunning is the phase of a cluster with all the components
+	// ready.
+	ClusterPhaseRunning = "Running"
+
+	// ClusterPhaseDegraded is the phase of a cluster with components that
+	// stopped being ready or are only partially ready.
+	ClusterPhaseDegraded = "Degraded"
+
+	// ClusterPhaseUpgrading is the phase of a running cluster with component
+	// rollouts in progress.
+	ClusterPhaseUpgrading = "Upgrading"
+
+	// ClusterPhaseTerminating is the phase of a cluster being deleted.
+	ClusterPhaseTerminating = "Terminating"
+)
+
 // StorageOSClusterStatus defines the observed state of StorageOSCluster
 type StorageOSClusterStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Phase is the phase of the StorageOS cluster.
+	// Phase is the phase of the StorageOS cluster. One of Pending, Creating,
+	// Running, Degraded, Upgrading or Terminating.
 	//+operator-sdk:csv:customresourcedefinitions:type=status
 	Phase string `json:"phase,omitempty"`
 
@@ -243,10 +565,37 @@ type NodeHealth struct {
 // MembersStatus stores the status details of cluster member nodes.
 type MembersStatus struct {
 	// Ready are the storageos cluster members that are ready to serve requests.
-	// The member names are the same as the node IPs.
+	// The member names are the same as the node IPs, or the node names when
+	// the IP isn't known yet.
 	Ready []string `json:"ready,omitempty"`
 	// Unready are the storageos cluster nodes not ready to serve requests.
+	// The member names are the same as the Ready member names.
 	Unready []string `json:"unready,omitempty"`
+	// Nodes are the details of the storageos cluster members, keyed by the
+	// kubernetes node name. Only the members scheduled on a node are listed.
+	Nodes map[string]MemberStatus `json:"nodes,omitempty"`
+}
+
+// MemberStatus stores the status details of a cluster member.
+type MemberStatus struct {
+	// IP is the IP of the node the member runs on.
+	IP string `json:"ip,omitempty"`
+	// PodName is the name of the member pod.
+	PodName string `json:"podName,omitempty"`
+	// Ready is true when the member pod is ready to serve requests.
+	Ready bool `json:"ready"`
+	// ContainersReady is the number of ready containers out of all the
+	// containers of the member pod, in the format "ready/total".
+	ContainersReady string `json:"containersReady,omitempty"`
+	// RestartCount is the total number of container restarts of the member
+	// pod.
+	RestartCount int32 `json:"restartCount"`
+	// Version is the StorageOS version of the member, based on the node
+	// container image tag.
+	Version string `json:"version,omitempty"`
+	// LastTransitionTime is the last time the ready status of the member
+	// changed.
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -13,6 +13,7 @@ import (
 	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kustomize/api/filesys"
 	kustomizetypes "sigs.k8s.io/kustomize/api/types"
@@ -37,10 +38,12 @@ const (
 type APIManagerOperand struct {
 	name            string
 	client          client.Client
+	apiReader       client.Reader
 	requires        []string
 	requeueStrategy operand.RequeueStrategy
 	fs              filesys.FileSystem
 	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
 }
 
 var _ operand.Operand = &APIManagerOperand{}
@@ -82,7 +85,9 @@ func (c *APIManagerOperand) Ensure(ctx context.Context, obj client.Object, owner
 		return nil, err
 	}
 
-	return nil, b.Apply(ctx)
+	// The api-manager Deployment is used to tell if the resources were created or updated.
+	primary := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "storageos-api-manager", Namespace: obj.GetNamespace()}}
+	return applyWithEvent(ctx, c.apiReader, c.recorder, b, obj, apiManagerPackage, primary)
 }
 
 func (c *APIManagerOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
@@ -94,7 +99,7 @@ func (c *APIManagerOperand) Delete(ctx context.Context, obj client.Object) (even
 		span.RecordError(err)
 		return nil, err
 	}
-	return nil, b.Delete(ctx)
+	return deleteWithEvent(ctx, b, obj, apiManagerPackage)
 }
 
 func getAPIManagerBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
@@ -125,7 +130,17 @@ func getAPIManagerBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.
 	// Add secret volume transform.
 	apiSecretVolTF := stransform.SetPodTemplateSecretVolumeFunc("api-secret", cluster.Spec.SecretRefName, nil)
 
-	deploymentTransforms = append(deploymentTransforms, apiSecretVolTF)
+	// Add pod placement transforms to spread the replicas.
+	antiAffinityTF := stransform.SetPodTemplatePodAntiAffinityFunc(getPodAntiAffinity(cluster, apiManagerComponent))
+	topologySpreadTF := stransform.SetPodTemplateTopologySpreadConstraintsFunc(getTopologySpreadConstraints(cluster, apiManagerComponent))
+
+	// Combine the default tolerations with the provided tolerations.
+	tolerationTF := stransform.SetPodTemplateTolerationFunc(getTolerations(cluster, getDefaultDeploymentTolerations()))
+
+	deploymentTransforms = append(deploymentTransforms, apiSecretVolTF, antiAffinityTF, topologySpreadTF, tolerationTF)
+
+	// Set the priority class of the distribution, if any.
+	deploymentTransforms = append(deploymentTransforms, getPriorityClassTransforms(cluster)...)
 
 	roleBindingTransforms := []transform.TransformFunc{}
 
@@ -139,15 +154,21 @@ func getAPIManagerBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.
 
 	roleBindingTransforms = append(roleBindingTransforms, daemonsetSASubjectNamespaceTF)
 
+	// Add the common and pod labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
+
 	return declarative.NewBuilder(apiManagerPackage, fs,
 		declarative.WithManifestTransform(transform.ManifestTransform{
 			"api-manager/deployment.yaml":                  deploymentTransforms,
 			"api-manager/key-management-role-binding.yaml": roleBindingTransforms,
 		}),
-		declarative.WithKustomizeMutationFunc([]kustomize.MutateFunc{
+		declarative.WithKustomizeMutationFunc(append([]kustomize.MutateFunc{
 			kustomize.AddNamespace(cluster.GetNamespace()),
 			kustomize.AddImages(images),
-		}),
+		}, labelsMutateFuncs...)),
 		declarative.WithKubectlClient(kcl),
 	)
 }
@@ -155,17 +176,21 @@ func getAPIManagerBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.
 func NewAPIManagerOperand(
 	name string,
 	client client.Client,
+	apiReader client.Reader,
 	requires []string,
 	requeueStrategy operand.RequeueStrategy,
 	fs filesys.FileSystem,
 	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
 ) *APIManagerOperand {
 	return &APIManagerOperand{
 		name:            name,
 		client:          client,
+		apiReader:       apiReader,
 		requires:        requires,
 		requeueStrategy: requeueStrategy,
 		fs:              fs,
 		kubectlClient:   kcl,
+		recorder:        recorder,
 	}
 }
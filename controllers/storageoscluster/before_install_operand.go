@@ -2,14 +2,18 @@ package storageoscluster
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/darkowlzz/operator-toolkit/declarative"
 	"github.com/darkowlzz/operator-toolkit/declarative/kubectl"
 	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
 	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kustomize/api/filesys"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
 )
 
 // beforeInstallPackage contains the resource manifests for beforeInstall
@@ -23,6 +27,7 @@ type BeforeInstallOperand struct {
 	requeueStrategy operand.RequeueStrategy
 	fs              filesys.FileSystem
 	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
 }
 
 var _ operand.Operand = &BeforeInstallOperand{}
@@ -45,7 +50,7 @@ func (bi *BeforeInstallOperand) Ensure(ctx context.Context, obj client.Object, o
 		return nil, err
 	}
 
-	return nil, b.Apply(ctx)
+	return applyWithEvent(ctx, bi.client, bi.recorder, b, obj, beforeInstallPackage, nil)
 }
 
 func (bi *BeforeInstallOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
@@ -58,11 +63,23 @@ func (bi *BeforeInstallOperand) Delete(ctx context.Context, obj client.Object) (
 		return nil, err
 	}
 
-	return nil, b.Delete(ctx)
+	return deleteWithEvent(ctx, b, obj, beforeInstallPackage)
 }
 
 func getBeforeInstallBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// Add the common labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
+
 	return declarative.NewBuilder(beforeInstallPackage, fs,
+		declarative.WithKustomizeMutationFunc(labelsMutateFuncs),
 		declarative.WithKubectlClient(kcl),
 	)
 }
@@ -74,6 +91,7 @@ func NewBeforeInstallOperand(
 	requeueStrategy operand.RequeueStrategy,
 	fs filesys.FileSystem,
 	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
 ) *BeforeInstallOperand {
 	return &BeforeInstallOperand{
 		name:            name,
@@ -82,5 +100,6 @@ func NewBeforeInstallOperand(
 		requeueStrategy: requeueStrategy,
 		fs:              fs,
 		kubectlClient:   kcl,
+		recorder:        recorder,
 	}
 }
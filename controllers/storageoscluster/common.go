@@ -1,9 +1,21 @@
 package storageoscluster
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
+	"github.com/darkowlzz/operator-toolkit/declarative/kustomize"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	kustomizetypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
 )
 
 // noResourceErr is used when an operand's resource builder can't create the
@@ -15,8 +27,27 @@ const (
 	// removed when disk space becomes available.
 	// NOTE: It is not an upstream constant.
 	TaintNodeOutOfDisk = "node.kubernetes.io/out-of-disk"
+
+	// appLabel is the label key set on all the storageos resources.
+	appLabel = "app"
+
+	// componentLabel is the label key used to identify the storageos
+	// component a resource belongs to.
+	componentLabel = "app.kubernetes.io/component"
+
+	// Component label values of the control Deployments.
+	apiManagerComponent = "storageos-api-manager"
+	csiComponent        = "csi"
+
+	// workloadKinds is a regex matching the kinds of the resources with a pod
+	// template.
+	workloadKinds = "Deployment|DaemonSet|StatefulSet"
 )
 
+// selectorLabels are the label keys used in the selectors of the storageos
+// resources. These can't be set in the common and pod labels.
+var selectorLabels = []string{appLabel, componentLabel}
+
 // getDefaultTolerations returns a collection of default tolerations for
 // StorageOS related resources.
 // NOTE: An empty effect matches all effects with the given key.
@@ -64,3 +95,325 @@ func getDefaultTolerations() []corev1.Toleration {
 		},
 	}
 }
+
+// getDefaultDeploymentTolerations returns a collection of default tolerations
+// for the StorageOS control Deployments. Unlike the node pods, the Deployment
+// pods are evicted from not-ready and unreachable nodes after a short delay.
+func getDefaultDeploymentTolerations() []corev1.Toleration {
+	tolerationSeconds := int64(30)
+	return []corev1.Toleration{
+		{
+			Key:      corev1.TaintNodeDiskPressure,
+			Operator: corev1.TolerationOpExists,
+		},
+		{
+			Key:               corev1.TaintNodeNotReady,
+			Operator:          corev1.TolerationOpExists,
+			Effect:            corev1.TaintEffectNoExecute,
+			TolerationSeconds: &tolerationSeconds,
+		},
+		{
+			Key:               corev1.TaintNodeUnreachable,
+			Operator:          corev1.TolerationOpExists,
+			Effect:            corev1.TaintEffectNoExecute,
+			TolerationSeconds: &tolerationSeconds,
+		},
+	}
+}
+
+// getTolerations combines the given default tolerations with the tolerations
+// in the cluster spec, based on the cluster tolerations mode, and returns the
+// result.
+func getTolerations(cluster *storageoscomv1.StorageOSCluster, defaults []corev1.Toleration) []corev1.Toleration {
+	switch cluster.Spec.TolerationsMode {
+	case storageoscomv1.TolerationsModeReplace:
+		return append([]corev1.Toleration{}, cluster.Spec.Tolerations...)
+	case storageoscomv1.TolerationsModeSubtract:
+		tolerations := []corev1.Toleration{}
+		for _, d := range defaults {
+			if !tolerationMatchesAny(d, cluster.Spec.Tolerations) {
+				tolerations = append(tolerations, d)
+			}
+		}
+		return tolerations
+	default:
+		return append(defaults, cluster.Spec.Tolerations...)
+	}
+}
+
+// tolerationMatchesAny checks if a toleration matches any of the given
+// tolerations by key. If a given toleration has an effect, the effect must
+// match too.
+func tolerationMatchesAny(toleration corev1.Toleration, tolerations []corev1.Toleration) bool {
+	for _, t := range tolerations {
+		if t.Key != toleration.Key {
+			continue
+		}
+		if t.Effect == "" || t.Effect == toleration.Effect {
+			return true
+		}
+	}
+	return false
+}
+
+// getComponentLabels returns the labels for selecting the pods of a given
+// storageos component.
+func getComponentLabels(component string) map[string]string {
+	return map[string]string{
+		appLabel:       "storageos",
+		componentLabel: component,
+	}
+}
+
+// getDefaultPodAntiAffinity returns the default pod anti-affinity for the
+// control Deployments of a given component. The replicas are preferably
+// scheduled on different nodes.
+func getDefaultPodAntiAffinity(component string) corev1.PodAntiAffinity {
+	return corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+			{
+				Weight: 100,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: getComponentLabels(component),
+					},
+					TopologyKey: corev1.LabelHostname,
+				},
+			},
+		},
+	}
+}
+
+// getDefaultTopologySpreadConstraints returns the default topology spread
+// constraints for the control Deployments of a given component. The replicas
+// are spread across zones when possible.
+func getDefaultTopologySpreadConstraints(component string) []corev1.TopologySpreadConstraint {
+	return []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       corev1.LabelTopologyZone,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: getComponentLabels(component),
+			},
+		},
+	}
+}
+
+// getPodAntiAffinity returns the pod anti-affinity for the control Deployments
+// of a given component. The anti-affinity from the cluster spec takes
+// precedence over the default. Terms without a label selector are scoped to
+// the component pods.
+func getPodAntiAffinity(cluster *storageoscomv1.StorageOSCluster, component string) corev1.PodAntiAffinity {
+	if cluster.Spec.PodAntiAffinity == nil {
+		return getDefaultPodAntiAffinity(component)
+	}
+
+	antiAffinity := cluster.Spec.PodAntiAffinity.DeepCopy()
+	for i := range antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		term := &antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i]
+		if term.LabelSelector == nil {
+			term.LabelSelector = &metav1.LabelSelector{MatchLabels: getComponentLabels(component)}
+		}
+	}
+	for i := range antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		term := &antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[i].PodAffinityTerm
+		if term.LabelSelector == nil {
+			term.LabelSelector = &metav1.LabelSelector{MatchLabels: getComponentLabels(component)}
+		}
+	}
+	return *antiAffinity
+}
+
+// getTopologySpreadConstraints returns the topology spread constraints for
+// the control Deployments of a given component. The constraints from the
+// cluster spec take precedence over the default. Constraints without a label
+// selector are scoped to the component pods.
+func getTopologySpreadConstraints(cluster *storageoscomv1.StorageOSCluster, component string) []corev1.TopologySpreadConstraint {
+	if len(cluster.Spec.TopologySpreadConstraints) == 0 {
+		return getDefaultTopologySpreadConstraints(component)
+	}
+
+	constraints := []corev1.TopologySpreadConstraint{}
+	for _, c := range cluster.Spec.TopologySpreadConstraints {
+		constraint := *c.DeepCopy()
+		if constraint.LabelSelector == nil {
+			constraint.LabelSelector = &metav1.LabelSelector{MatchLabels: getComponentLabels(component)}
+		}
+		constraints = append(constraints, constraint)
+	}
+	return constraints
+}
+
+// contains checks if a string is in a list of strings.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateLabels checks that the common and pod labels in the cluster spec
+// don't override any of the labels used in the selectors.
+func ValidateLabels(cluster *storageoscomv1.StorageOSCluster) error {
+	for _, key := range selectorLabels {
+		if _, ok := cluster.Spec.CommonLabels[key]; ok {
+			return fmt.Errorf("commonLabels: label %q is reserved", key)
+		}
+		if _, ok := cluster.Spec.PodLabels[key]; ok {
+			return fmt.Errorf("podLabels: label %q is reserved", key)
+		}
+	}
+	return nil
+}
+
+// getLabelsMutateFuncs returns kustomize mutations to add the common and pod
+// labels and annotations in the cluster spec to the resources. Strategic
+// merge patches are used instead of the kustomize common labels to avoid
+// changing the label selectors, which are immutable in most of the resources.
+func getLabelsMutateFuncs(cluster *storageoscomv1.StorageOSCluster) ([]kustomize.MutateFunc, error) {
+	if err := ValidateLabels(cluster); err != nil {
+		return nil, err
+	}
+
+	mutateFuncs := []kustomize.MutateFunc{}
+
+	// Add the common labels and annotations to the metadata of all the
+	// resources.
+	if len(cluster.Spec.CommonLabels) > 0 || len(cluster.Spec.CommonAnnotations) > 0 {
+		patch, err := getMetadataPatch(map[string]interface{}{
+			"metadata": getObjectMeta(cluster.Spec.CommonLabels, cluster.Spec.CommonAnnotations),
+		})
+		if err != nil {
+			return nil, err
+		}
+		mutateFuncs = append(mutateFuncs, addPatch(patch, &kustomizetypes.Selector{}))
+	}
+
+	// Add the common and pod labels and annotations to the pod templates.
+	podLabels := mergeMaps(cluster.Spec.CommonLabels, cluster.Spec.PodLabels)
+	podAnnotations := mergeMaps(cluster.Spec.CommonAnnotations, cluster.Spec.PodAnnotations)
+	if len(podLabels) > 0 || len(podAnnotations) > 0 {
+		patch, err := getMetadataPatch(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"metadata": getObjectMeta(podLabels, podAnnotations),
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		target := &kustomizetypes.Selector{}
+		target.Kind = workloadKinds
+		mutateFuncs = append(mutateFuncs, addPatch(patch, target))
+	}
+
+	return mutateFuncs, nil
+}
+
+// getObjectMeta returns an object metadata with the given labels and
+// annotations, omitting the empty ones.
+func getObjectMeta(labels, annotations map[string]string) map[string]interface{} {
+	meta := map[string]interface{}{}
+	if len(labels) > 0 {
+		meta["labels"] = labels
+	}
+	if len(annotations) > 0 {
+		meta["annotations"] = annotations
+	}
+	return meta
+}
+
+// getMetadataPatch returns a strategic merge patch with the given content.
+// The patch identity is a placeholder. Kustomize sets it to the identity of
+// each of the target resources when applying the patch.
+func getMetadataPatch(content map[string]interface{}) (string, error) {
+	content["apiVersion"] = "v1"
+	content["kind"] = "Placeholder"
+	meta, ok := content["metadata"].(map[string]interface{})
+	if !ok {
+		meta = map[string]interface{}{}
+		content["metadata"] = meta
+	}
+	meta["name"] = "placeholder"
+
+	b, err := yaml.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal patch: %w", err)
+	}
+	return string(b), nil
+}
+
+// addPatch returns a MutateFunc which adds a patch for the given target to a
+// kustomization.
+func addPatch(patch string, target *kustomizetypes.Selector) kustomize.MutateFunc {
+	return func(k *kustomizetypes.Kustomization) {
+		k.Patches = append(k.Patches, kustomizetypes.Patch{Patch: patch, Target: target})
+	}
+}
+
+// mergeMaps returns a new map with the content of all the given maps. Values
+// in the latter maps take precedence.
+func mergeMaps(maps ...map[string]string) map[string]string {
+	result := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// isKindServed checks if the API server serves the given kind. The client's
+// REST mapper rediscovers the served kinds when a kind isn't found, so kinds
+// installed after the operator started are detected.
+func isKindServed(cl client.Client, gk schema.GroupKind) (bool, error) {
+	if _, err := cl.RESTMapper().RESTMapping(gk); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// isGroupKindsServed checks if the API server serves all the given kinds of an
+// API group. It's used to check if the CRDs of optional components, installed
+// separately, are installed.
+func isGroupKindsServed(cl client.Client, group string, kinds []string) (bool, error) {
+	for _, kind := range kinds {
+		served, err := isKindServed(cl, schema.GroupKind{Group: group, Kind: kind})
+		if err != nil || !served {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// deleteStaleObjects deletes the cluster scoped objects of the given kind that
+// have the given labels, except the object named keep. It removes the objects
+// left behind when a component's object is renamed or the component is
+// disabled. It returns the names of the deleted objects.
+func deleteStaleObjects(ctx context.Context, cl client.Client, gvk schema.GroupVersionKind, labels map[string]string, keep string) ([]string, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := cl.List(ctx, list, client.MatchingLabels(labels)); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", gvk.Kind, err)
+	}
+
+	deleted := []string{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		if obj.GetName() == keep {
+			continue
+		}
+		if err := cl.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return deleted, fmt.Errorf("failed to delete %s %q: %w", gvk.Kind, obj.GetName(), err)
+		}
+		deleted = append(deleted, obj.GetName())
+	}
+	return deleted, nil
+}
@@ -0,0 +1,401 @@
+package storageoscluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/declarative/kustomize"
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	kustomizetypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestGetPodAntiAffinity(t *testing.T) {
+	customSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}}
+
+	cases := []struct {
+		name         string
+		antiAffinity *corev1.PodAntiAffinity
+		want         corev1.PodAntiAffinity
+	}{
+		{
+			name: "default",
+			want: getDefaultPodAntiAffinity(csiComponent),
+		},
+		{
+			name: "override with component scoped term",
+			antiAffinity: &corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+					{TopologyKey: corev1.LabelHostname},
+				},
+			},
+			want: corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+					{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: getComponentLabels(csiComponent)},
+						TopologyKey:   corev1.LabelHostname,
+					},
+				},
+			},
+		},
+		{
+			name: "override with custom selector",
+			antiAffinity: &corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+					{
+						Weight: 10,
+						PodAffinityTerm: corev1.PodAffinityTerm{
+							LabelSelector: customSelector,
+							TopologyKey:   corev1.LabelTopologyZone,
+						},
+					},
+				},
+			},
+			want: corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+					{
+						Weight: 10,
+						PodAffinityTerm: corev1.PodAffinityTerm{
+							LabelSelector: customSelector,
+							TopologyKey:   corev1.LabelTopologyZone,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				Spec: storageoscomv1.StorageOSClusterSpec{
+					PodAntiAffinity: tc.antiAffinity,
+				},
+			}
+			assert.Equal(t, tc.want, getPodAntiAffinity(cluster, csiComponent))
+		})
+	}
+}
+
+func TestGetTopologySpreadConstraints(t *testing.T) {
+	cases := []struct {
+		name        string
+		constraints []corev1.TopologySpreadConstraint
+		want        []corev1.TopologySpreadConstraint
+	}{
+		{
+			name: "default",
+			want: getDefaultTopologySpreadConstraints(apiManagerComponent),
+		},
+		{
+			name: "override with component scoped constraint",
+			constraints: []corev1.TopologySpreadConstraint{
+				{
+					MaxSkew:           2,
+					TopologyKey:       corev1.LabelHostname,
+					WhenUnsatisfiable: corev1.DoNotSchedule,
+				},
+			},
+			want: []corev1.TopologySpreadConstraint{
+				{
+					MaxSkew:           2,
+					TopologyKey:       corev1.LabelHostname,
+					WhenUnsatisfiable: corev1.DoNotSchedule,
+					LabelSelector:     &metav1.LabelSelector{MatchLabels: getComponentLabels(apiManagerComponent)},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				Spec: storageoscomv1.StorageOSClusterSpec{
+					TopologySpreadConstraints: tc.constraints,
+				},
+			}
+			assert.Equal(t, tc.want, getTopologySpreadConstraints(cluster, apiManagerComponent))
+		})
+	}
+}
+
+func TestGetTolerations(t *testing.T) {
+	defaults := []corev1.Toleration{
+		{
+			Key:      corev1.TaintNodeNotReady,
+			Operator: corev1.TolerationOpExists,
+		},
+		{
+			Key:      corev1.TaintNodeUnschedulable,
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		},
+	}
+	custom := corev1.Toleration{
+		Key:      "foo",
+		Operator: corev1.TolerationOpEqual,
+		Value:    "bar",
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+
+	cases := []struct {
+		name        string
+		mode        storageoscomv1.TolerationsMode
+		tolerations []corev1.Toleration
+		want        []corev1.Toleration
+	}{
+		{
+			name: "defaults only",
+			want: defaults,
+		},
+		{
+			name:        "append",
+			tolerations: []corev1.Toleration{custom},
+			want:        append(append([]corev1.Toleration{}, defaults...), custom),
+		},
+		{
+			name:        "replace",
+			mode:        storageoscomv1.TolerationsModeReplace,
+			tolerations: []corev1.Toleration{custom},
+			want:        []corev1.Toleration{custom},
+		},
+		{
+			name: "replace with none",
+			mode: storageoscomv1.TolerationsModeReplace,
+			want: []corev1.Toleration{},
+		},
+		{
+			name:        "subtract by key",
+			mode:        storageoscomv1.TolerationsModeSubtract,
+			tolerations: []corev1.Toleration{{Key: corev1.TaintNodeUnschedulable}},
+			want:        defaults[:1],
+		},
+		{
+			name: "subtract with mismatched effect",
+			mode: storageoscomv1.TolerationsModeSubtract,
+			tolerations: []corev1.Toleration{
+				{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoExecute},
+			},
+			want: defaults,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				Spec: storageoscomv1.StorageOSClusterSpec{
+					Tolerations:     tc.tolerations,
+					TolerationsMode: tc.mode,
+				},
+			}
+			// Pass a copy of the defaults to avoid modifying the shared slice.
+			got := getTolerations(cluster, append([]corev1.Toleration{}, defaults...))
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestGetLabelsMutateFuncs(t *testing.T) {
+	cases := []struct {
+		name           string
+		spec           storageoscomv1.StorageOSClusterSpec
+		wantErr        bool
+		wantPatches    int
+		wantPatchKinds []string
+	}{
+		{
+			name: "no labels or annotations",
+		},
+		{
+			name: "common labels",
+			spec: storageoscomv1.StorageOSClusterSpec{
+				CommonLabels: map[string]string{"team": "storage"},
+			},
+			wantPatches:    2,
+			wantPatchKinds: []string{"", workloadKinds},
+		},
+		{
+			name: "pod annotations only",
+			spec: storageoscomv1.StorageOSClusterSpec{
+				PodAnnotations: map[string]string{"foo": "bar"},
+			},
+			wantPatches:    1,
+			wantPatchKinds: []string{workloadKinds},
+		},
+		{
+			name: "reserved common label",
+			spec: storageoscomv1.StorageOSClusterSpec{
+				CommonLabels: map[string]string{appLabel: "foo"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "reserved pod label",
+			spec: storageoscomv1.StorageOSClusterSpec{
+				PodLabels: map[string]string{componentLabel: "foo"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{Spec: tc.spec}
+			mutateFuncs, err := getLabelsMutateFuncs(cluster)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+
+			k := &kustomizetypes.Kustomization{}
+			kustomize.Mutate(k, mutateFuncs)
+			assert.Len(t, k.Patches, tc.wantPatches)
+			for i, patch := range k.Patches {
+				assert.Equal(t, tc.wantPatchKinds[i], patch.Target.Kind)
+				// Selector labels must never be patched.
+				assert.NotContains(t, patch.Patch, "matchLabels")
+			}
+		})
+	}
+}
+
+func TestLabelsRenderedInPackage(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+	}
+	cluster.Spec.SecretRefName = "some-secret"
+	cluster.Spec.CommonLabels = map[string]string{"team": "storage"}
+	cluster.Spec.CommonAnnotations = map[string]string{"owner": "ops"}
+	cluster.Spec.PodLabels = map[string]string{"tier": "data"}
+	cluster.Spec.PodAnnotations = map[string]string{"scrape": "true"}
+
+	b, err := getNodeBuilder(fs, cluster, nil, nil)
+	assert.Nil(t, err)
+
+	var ds *appsv1.DaemonSet
+	var cm *corev1.ConfigMap
+	for _, doc := range strings.Split(b.Manifest(), "\n---\n") {
+		switch {
+		case strings.Contains(doc, "kind: DaemonSet"):
+			ds = &appsv1.DaemonSet{}
+			assert.Nil(t, yaml.Unmarshal([]byte(doc), ds))
+		case strings.Contains(doc, "kind: ConfigMap"):
+			cm = &corev1.ConfigMap{}
+			assert.Nil(t, yaml.Unmarshal([]byte(doc), cm))
+		}
+	}
+	assert.NotNil(t, ds)
+	assert.NotNil(t, cm)
+
+	// Common labels and annotations are set on all the resources.
+	for _, meta := range []metav1.ObjectMeta{ds.ObjectMeta, cm.ObjectMeta} {
+		assert.Equal(t, "storage", meta.Labels["team"], meta.Name)
+		assert.Equal(t, "ops", meta.Annotations["owner"], meta.Name)
+		// Pod labels and annotations are only set on the pod templates.
+		assert.NotContains(t, meta.Labels, "tier", meta.Name)
+		assert.NotContains(t, meta.Annotations, "scrape", meta.Name)
+	}
+
+	// The pod template gets the common and pod labels and annotations.
+	podMeta := ds.Spec.Template.ObjectMeta
+	assert.Equal(t, "storage", podMeta.Labels["team"])
+	assert.Equal(t, "data", podMeta.Labels["tier"])
+	assert.Equal(t, "ops", podMeta.Annotations["owner"])
+	assert.Equal(t, "true", podMeta.Annotations["scrape"])
+	// The existing pod labels are kept.
+	assert.Equal(t, "storageos", podMeta.Labels[appLabel])
+
+	// Selectors aren't changed.
+	assert.NotContains(t, ds.Spec.Selector.MatchLabels, "team")
+	assert.NotContains(t, ds.Spec.Selector.MatchLabels, "tier")
+}
+
+func TestIsGroupKindsServed(t *testing.T) {
+	gv := schema.GroupVersion{Group: "example.com", Version: "v1"}
+	kinds := []string{"Foo", "Bar"}
+
+	cases := []struct {
+		name   string
+		served []string
+		want   bool
+	}{
+		{
+			name: "no kinds served",
+		},
+		{
+			name:   "some kinds served",
+			served: []string{"Foo"},
+		},
+		{
+			name:   "all kinds served",
+			served: kinds,
+			want:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv})
+			for _, kind := range tc.served {
+				mapper.Add(gv.WithKind(kind), meta.RESTScopeNamespace)
+			}
+			cl := &restMapperClient{Client: fake.NewClientBuilder().Build(), mapper: mapper}
+
+			served, err := isGroupKindsServed(cl, gv.Group, kinds)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, served)
+		})
+	}
+}
+
+func TestDeleteStaleObjects(t *testing.T) {
+	vsc := func(name string, labels map[string]string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(volumeSnapshotClassGVK)
+		obj.SetName(name)
+		obj.SetLabels(labels)
+		return obj
+	}
+
+	// The fake client only lists registered kinds.
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(volumeSnapshotClassGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(volumeSnapshotClassGVK.GroupVersion().WithKind("VolumeSnapshotClassList"), &unstructured.UnstructuredList{})
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		vsc("current", volumeSnapshotClassLabels),
+		vsc("renamed", volumeSnapshotClassLabels),
+		vsc("other", map[string]string{"foo": "bar"}),
+	).Build()
+
+	deleted, err := deleteStaleObjects(context.TODO(), cl, volumeSnapshotClassGVK, volumeSnapshotClassLabels, "current")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"renamed"}, deleted)
+
+	for name, wantExists := range map[string]bool{"current": true, "renamed": false, "other": true} {
+		err := cl.Get(context.TODO(), client.ObjectKey{Name: name}, vsc(name, nil))
+		assert.Equal(t, wantExists, err == nil, name)
+	}
+}
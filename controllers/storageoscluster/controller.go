@@ -3,6 +3,7 @@ package storageoscluster
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	compositev1 "github.com/darkowlzz/operator-toolkit/controller/composite/v1"
 	"github.com/darkowlzz/operator-toolkit/object"
@@ -17,6 +18,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/internal/image"
+	"github.com/storageos/operator/internal/metrics"
 )
 
 const (
@@ -24,14 +27,31 @@ const (
 	nodeReadyType       = "NodeReady"
 	apiManagerReadyType = "APIManagerReady"
 	csiReadyType        = "CSIReady"
+	snapshotReadyType   = "SnapshotReady"
+	monitoringReadyType = "MonitoringReady"
 
 	readyReason    = "Ready"
 	notReadyReason = "NotReady"
+
+	// Reasons of the component conditions, based on the ready and desired
+	// replicas of the component workload.
+	availableReason   = "Available"
+	progressingReason = "Progressing"
+	degradedReason    = "Degraded"
+	notFoundReason    = "NotFound"
+
+	// crdsNotFoundReason is used when the CRDs required by a component aren't
+	// installed.
+	crdsNotFoundReason = "CRDsNotFound"
 )
 
 type StorageOSClusterController struct {
 	Operator operatorv1.Operator
 	Client   client.Client
+
+	// K8sDistro is the detected kubernetes distribution. It's used when the
+	// cluster doesn't specify the distribution.
+	K8sDistro string
 }
 
 var _ compositev1.Controller = &StorageOSClusterController{}
@@ -58,13 +78,28 @@ func (c *StorageOSClusterController) Initialize(ctx context.Context, obj client.
 func (c *StorageOSClusterController) Operate(ctx context.Context, obj client.Object) (result ctrl.Result, err error) {
 	_, _, _, log := instrumentation.Start(ctx, "StorageOSClusterController.Operate")
 	log.Info("ensuring cluster with the current configuration", "cluster-name", obj.GetName(), "namespace", obj.GetNamespace())
-	return c.Operator.Ensure(ctx, obj, object.OwnerReferenceFromObject(obj))
+
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return ctrl.Result{}, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// Apply the distribution defaults before running the operands.
+	return c.Operator.Ensure(ctx, withDistroDefaults(cluster, c.K8sDistro), object.OwnerReferenceFromObject(obj))
 }
 
 func (c *StorageOSClusterController) Cleanup(ctx context.Context, obj client.Object) (result ctrl.Result, err error) {
 	_, _, _, log := instrumentation.Start(ctx, "StorageOSClusterController.Operate")
 	log.Info("deleting cluster", "cluster-name", obj.GetName(), "namespace", obj.GetNamespace())
-	return c.Operator.Cleanup(ctx, obj)
+
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return ctrl.Result{}, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// Apply the distribution defaults to delete the same resources that were
+	// created.
+	return c.Operator.Cleanup(ctx, withDistroDefaults(cluster, c.K8sDistro))
 }
 
 func (c *StorageOSClusterController) UpdateStatus(ctx context.Context, obj client.Object) error {
@@ -81,6 +116,7 @@ func (c *StorageOSClusterController) UpdateStatus(ctx context.Context, obj clien
 		if apierrors.IsNotFound(getErr) {
 			// If the object is not found, the object may have been deleted by
 			// cleanup handler call before UpdateStatus was called.
+			metrics.DeleteClusterMetrics(cluster.GetName(), cluster.GetNamespace())
 			return nil
 		}
 		return fmt.Errorf("failed to get StorageOSCluster %q: %w", cluster.GetName(), getErr)
@@ -101,39 +137,35 @@ func (c *StorageOSClusterController) UpdateStatus(ctx context.Context, obj clien
 	csiCondition := getCSICondition(ctx, c.Client, obj.GetNamespace(), log)
 	meta.SetStatusCondition(&cluster.Status.Conditions, csiCondition)
 
-	conditions := cluster.Status.Conditions
-
-	// Evaluate the cluster phase based on the component status.
-	phase := "Pending"
-	if meta.IsStatusConditionTrue(conditions, schedulerReadyType) ||
-		meta.IsStatusConditionTrue(conditions, nodeReadyType) ||
-		meta.IsStatusConditionTrue(conditions, apiManagerReadyType) ||
-		meta.IsStatusConditionTrue(conditions, csiReadyType) {
-		// Some components are ready, Creating phase.
-		phase = "Creating"
+	// The snapshot condition is only reported when snapshots are enabled.
+	if cluster.Spec.Snapshot.Enable {
+		snapshotCondition := getCRDFeatureCondition(c.Client, log, snapshotFeature)
+		meta.SetStatusCondition(&cluster.Status.Conditions, snapshotCondition)
+	} else {
+		meta.RemoveStatusCondition(&cluster.Status.Conditions, snapshotReadyType)
 	}
 
-	// Evaluate the cluster condition based on the component status.
-	if meta.IsStatusConditionTrue(conditions, schedulerReadyType) &&
-		meta.IsStatusConditionTrue(conditions, nodeReadyType) &&
-		meta.IsStatusConditionTrue(conditions, apiManagerReadyType) &&
-		meta.IsStatusConditionTrue(conditions, csiReadyType) {
-		// Remove progressing condition and set cluster Ready status.
-		meta.RemoveStatusCondition(&cluster.Status.Conditions, "Progressing")
-		readyCondition := metav1.Condition{
-			Type:    "Ready",
-			Status:  metav1.ConditionTrue,
-			Reason:  "Ready",
-			Message: "Cluster Ready",
-		}
-		meta.SetStatusCondition(&cluster.Status.Conditions, readyCondition)
-
-		// All the components are ready, Running phase.
-		phase = "Running"
+	// The monitoring condition is only reported when monitoring is enabled.
+	if cluster.Spec.Monitoring.Enable {
+		monitoringCondition := getCRDFeatureCondition(c.Client, log, monitoringFeature)
+		meta.SetStatusCondition(&cluster.Status.Conditions, monitoringCondition)
+	} else {
+		meta.RemoveStatusCondition(&cluster.Status.Conditions, monitoringReadyType)
 	}
 
-	// Set the cluster phase.
-	cluster.Status.Phase = phase
+	// Evaluate the cluster phase and conditions based on the component
+	// status.
+	deleting := !cluster.GetDeletionTimestamp().IsZero()
+	cluster.Status.Phase = getClusterPhase(deleting, cluster.Status.Phase, cluster.Status.Conditions)
+	setClusterConditions(&cluster.Status.Conditions, cluster.Status.Phase)
+
+	// Update the cluster metrics. The metrics of a deleted cluster are
+	// removed.
+	if deleting {
+		metrics.DeleteClusterMetrics(cluster.GetName(), cluster.GetNamespace())
+	} else {
+		setClusterMetrics(ctx, c.Client, cluster, log)
+	}
 
 	// Get the control-plane instances and set them in the members status.
 	members, err := getControlPlaneMembers(ctx, c.Client, obj.GetNamespace(), log)
@@ -149,25 +181,8 @@ func (c *StorageOSClusterController) UpdateStatus(ctx context.Context, obj clien
 }
 
 func getSchedulerCondition(ctx context.Context, cl client.Client, namespace string, log logr.Logger) metav1.Condition {
-	schedulerCondition := metav1.Condition{
-		Type:    schedulerReadyType,
-		Status:  metav1.ConditionFalse,
-		Reason:  notReadyReason,
-		Message: "Scheduler Not Ready",
-	}
-	schedulerDep := &appsv1.Deployment{}
 	schedulerKey := client.ObjectKey{Name: "storageos-scheduler", Namespace: namespace}
-	if err := cl.Get(ctx, schedulerKey, schedulerDep); err != nil {
-		if !apierrors.IsNotFound(err) {
-			log.Error(err, "failed to get storageos-scheduler status")
-		}
-	}
-	if schedulerDep.Status.ReadyReplicas == schedulerDep.Status.Replicas {
-		schedulerCondition.Status = metav1.ConditionTrue
-		schedulerCondition.Reason = readyReason
-		schedulerCondition.Message = "Scheduler Ready"
-	}
-	return schedulerCondition
+	return getDeploymentCondition(ctx, cl, schedulerKey, schedulerReadyType, "Scheduler", log)
 }
 
 func getNodeCondition(ctx context.Context, cl client.Client, namespace string, log logr.Logger) metav1.Condition {
@@ -180,60 +195,156 @@ func getNodeCondition(ctx context.Context, cl client.Client, namespace string, l
 	nodeDS := &appsv1.DaemonSet{}
 	nodeKey := client.ObjectKey{Name: "storageos-daemonset", Namespace: namespace}
 	if err := cl.Get(ctx, nodeKey, nodeDS); err != nil {
-		if !apierrors.IsNotFound(err) {
-			log.Error(err, "failed to get node status")
+		if apierrors.IsNotFound(err) {
+			nodeCondition.Reason = notFoundReason
+			nodeCondition.Message = "Node DaemonSet not found"
+			return nodeCondition
 		}
+		log.Error(err, "failed to get node status")
+		return nodeCondition
 	}
-	if nodeDS.Status.NumberReady > 0 {
+
+	desired := nodeDS.Status.DesiredNumberScheduled
+	ready := nodeDS.Status.NumberReady
+	updated := nodeDS.Status.UpdatedNumberScheduled
+
+	switch {
+	case nodeDS.Status.ObservedGeneration < nodeDS.Generation:
+		// The status is stale until the latest spec is observed.
+		nodeCondition.Reason = progressingReason
+		nodeCondition.Message = fmt.Sprintf("Node rollout pending, %d/%d pods ready", ready, desired)
+	case updated < desired:
+		nodeCondition.Reason = progressingReason
+		nodeCondition.Message = fmt.Sprintf("Node rollout in progress, %d/%d pods updated, %d/%d pods ready", updated, desired, ready, desired)
+	case desired > 0 && ready >= desired:
 		nodeCondition.Status = metav1.ConditionTrue
-		nodeCondition.Reason = readyReason
-		nodeCondition.Message = "Node Ready"
+		nodeCondition.Reason = availableReason
+		nodeCondition.Message = fmt.Sprintf("Node Ready, %d/%d pods ready", ready, desired)
+	case ready > 0:
+		nodeCondition.Reason = degradedReason
+		nodeCondition.Message = fmt.Sprintf("Node Degraded, %d/%d pods ready", ready, desired)
+	default:
+		nodeCondition.Reason = progressingReason
+		nodeCondition.Message = fmt.Sprintf("Node Not Ready, %d/%d pods ready", ready, desired)
 	}
 	return nodeCondition
 }
 
 func getAPIManagerCondition(ctx context.Context, cl client.Client, namespace string, log logr.Logger) metav1.Condition {
-	apiManagerCondition := metav1.Condition{
-		Type:    apiManagerReadyType,
+	amKey := client.ObjectKey{Name: "storageos-api-manager", Namespace: namespace}
+	return getDeploymentCondition(ctx, cl, amKey, apiManagerReadyType, "APIManager", log)
+}
+
+func getCSICondition(ctx context.Context, cl client.Client, namespace string, log logr.Logger) metav1.Condition {
+	csiKey := client.ObjectKey{Name: "storageos-csi-helper", Namespace: namespace}
+	return getDeploymentCondition(ctx, cl, csiKey, csiReadyType, "CSI", log)
+}
+
+// getDeploymentCondition returns the condition of a component deployment.
+// The component is available only when all the desired replicas of the
+// latest observed spec are available.
+func getDeploymentCondition(ctx context.Context, cl client.Client, key client.ObjectKey, condType string, component string, log logr.Logger) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    condType,
 		Status:  metav1.ConditionFalse,
 		Reason:  notReadyReason,
-		Message: "APIManager Not Ready",
+		Message: fmt.Sprintf("%s Not Ready", component),
 	}
-	amDep := &appsv1.Deployment{}
-	amKey := client.ObjectKey{Name: "storageos-api-manager", Namespace: namespace}
-	if err := cl.Get(ctx, amKey, amDep); err != nil {
-		if !apierrors.IsNotFound(err) {
-			log.Error(err, "failed to get api-manager status")
+	dep := &appsv1.Deployment{}
+	if err := cl.Get(ctx, key, dep); err != nil {
+		if apierrors.IsNotFound(err) {
+			condition.Reason = notFoundReason
+			condition.Message = fmt.Sprintf("%s Deployment not found", component)
+			return condition
 		}
+		log.Error(err, "failed to get deployment status", "name", key.Name)
+		return condition
 	}
-	if amDep.Status.AvailableReplicas > 0 {
-		apiManagerCondition.Status = metav1.ConditionTrue
-		apiManagerCondition.Reason = readyReason
-		apiManagerCondition.Message = "APIManager Ready"
+
+	// Replicas defaults to 1 when unset.
+	desired := int32(1)
+	if dep.Spec.Replicas != nil {
+		desired = *dep.Spec.Replicas
+	}
+	available := dep.Status.AvailableReplicas
+	updated := dep.Status.UpdatedReplicas
+
+	switch {
+	case dep.Status.ObservedGeneration < dep.Generation:
+		// The status is stale until the latest spec is observed.
+		condition.Reason = progressingReason
+		condition.Message = fmt.Sprintf("%s rollout pending, %d/%d replicas available", component, available, desired)
+	case updated < desired || dep.Status.Replicas > updated:
+		// Old replicas are still being replaced.
+		condition.Reason = progressingReason
+		condition.Message = fmt.Sprintf("%s rollout in progress, %d/%d replicas updated, %d/%d replicas available", component, updated, desired, available, desired)
+	case available >= desired:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = availableReason
+		condition.Message = fmt.Sprintf("%s Ready, %d/%d replicas available", component, available, desired)
+	case available > 0:
+		condition.Reason = degradedReason
+		condition.Message = fmt.Sprintf("%s Degraded, %d/%d replicas available", component, available, desired)
+	default:
+		condition.Reason = progressingReason
+		condition.Message = fmt.Sprintf("%s Not Ready, %d/%d replicas available", component, available, desired)
 	}
-	return apiManagerCondition
+	return condition
 }
 
-func getCSICondition(ctx context.Context, cl client.Client, namespace string, log logr.Logger) metav1.Condition {
-	csiCondition := metav1.Condition{
-		Type:    csiReadyType,
+// crdFeature is a cluster feature that requires CRDs installed separately.
+type crdFeature struct {
+	// conditionType is the type of the feature's status condition.
+	conditionType string
+	// name is the feature name used in the condition messages.
+	name string
+	// group and kinds are the API group and kinds of the required CRDs.
+	group string
+	kinds []string
+	// notFoundMessage is the condition message when the CRDs aren't found.
+	notFoundMessage string
+}
+
+var (
+	snapshotFeature = crdFeature{
+		conditionType:   snapshotReadyType,
+		name:            "Snapshot",
+		group:           volumeSnapshotGroup,
+		kinds:           volumeSnapshotKinds,
+		notFoundMessage: fmt.Sprintf("Volume snapshot CRDs (%s) not found, install the CSI external snapshotter CRDs to enable volume snapshots", volumeSnapshotGroup),
+	}
+	monitoringFeature = crdFeature{
+		conditionType:   monitoringReadyType,
+		name:            "Monitoring",
+		group:           monitoringGroup,
+		kinds:           monitoringKinds,
+		notFoundMessage: fmt.Sprintf("Prometheus Operator CRDs (%s) not found, install the Prometheus Operator to enable monitoring", monitoringGroup),
+	}
+)
+
+// getCRDFeatureCondition returns the status condition of a feature that
+// requires CRDs. The feature is ready when the API server serves the CRDs.
+func getCRDFeatureCondition(cl client.Client, log logr.Logger, feature crdFeature) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    feature.conditionType,
 		Status:  metav1.ConditionFalse,
 		Reason:  notReadyReason,
-		Message: "CSI Not Ready",
+		Message: fmt.Sprintf("%s Not Ready", feature.name),
 	}
-	csiDep := &appsv1.Deployment{}
-	csiKey := client.ObjectKey{Name: "storageos-csi-helper", Namespace: namespace}
-	if err := cl.Get(ctx, csiKey, csiDep); err != nil {
-		if !apierrors.IsNotFound(err) {
-			log.Error(err, "failed to get csi-helper status")
-		}
+	served, err := isGroupKindsServed(cl, feature.group, feature.kinds)
+	if err != nil {
+		log.Error(err, "failed to check CRDs", "group", feature.group)
+		return condition
 	}
-	if csiDep.Status.AvailableReplicas > 0 {
-		csiCondition.Status = metav1.ConditionTrue
-		csiCondition.Reason = readyReason
-		csiCondition.Message = "CSI Ready"
+	if !served {
+		condition.Reason = crdsNotFoundReason
+		condition.Message = feature.notFoundMessage
+		return condition
 	}
-	return csiCondition
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = readyReason
+	condition.Message = fmt.Sprintf("%s Ready", feature.name)
+	return condition
 }
 
 // getLabelsForControlPlane returns the labels for selecting storageos
@@ -265,13 +376,70 @@ func getControlPlaneMembers(ctx context.Context, cl client.Client, namespace str
 		return ms, err
 	}
 
-	for _, pod := range cpPods.Items {
-		if pod.Status.Phase == corev1.PodRunning {
-			ms.Ready = append(ms.Ready, pod.Status.HostIP)
+	// Sort the pods to keep the member lists stable across updates.
+	pods := cpPods.Items
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+	for _, pod := range pods {
+		member := getMemberStatus(pod)
+
+		// Pending pods may not have a host IP yet, fall back to the node and
+		// pod names to count them.
+		name := member.IP
+		if name == "" {
+			name = pod.Spec.NodeName
+		}
+		if name == "" {
+			name = pod.Name
+		}
+		if member.Ready {
+			ms.Ready = append(ms.Ready, name)
 		} else {
-			ms.Unready = append(ms.Unready, pod.Status.HostIP)
+			ms.Unready = append(ms.Unready, name)
 		}
+
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		if ms.Nodes == nil {
+			ms.Nodes = map[string]storageoscomv1.MemberStatus{}
+		}
+		ms.Nodes[pod.Spec.NodeName] = member
 	}
 
 	return ms, nil
 }
+
+// getMemberStatus returns the member status of a control-plane pod. A member
+// is ready when its pod is ready.
+func getMemberStatus(pod corev1.Pod) storageoscomv1.MemberStatus {
+	member := storageoscomv1.MemberStatus{
+		IP:      pod.Status.HostIP,
+		PodName: pod.Name,
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			member.Ready = c.Status == corev1.ConditionTrue
+			ltt := c.LastTransitionTime
+			member.LastTransitionTime = &ltt
+		}
+	}
+
+	ready := 0
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Ready {
+			ready++
+		}
+		member.RestartCount += cs.RestartCount
+	}
+	member.ContainersReady = fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers))
+
+	for _, c := range pod.Spec.Containers {
+		if c.Name == storageosContainer {
+			_, member.Version, _ = image.Split(c.Image)
+		}
+	}
+
+	return member
+}
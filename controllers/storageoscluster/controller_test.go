@@ -0,0 +1,295 @@
+package storageoscluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestGetDeploymentCondition(t *testing.T) {
+	replicas := int32(2)
+	key := client.ObjectKey{Name: "storageos-api-manager", Namespace: "some-ns"}
+
+	cases := []struct {
+		name        string
+		status      *appsv1.DeploymentStatus
+		generation  int64
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:        "not found",
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  notFoundReason,
+			wantMessage: "APIManager Deployment not found",
+		},
+		{
+			name:        "all available",
+			status:      &appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			generation:  1,
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  availableReason,
+			wantMessage: "APIManager Ready, 2/2 replicas available",
+		},
+		{
+			name:        "partially available",
+			status:      &appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 1},
+			generation:  1,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  degradedReason,
+			wantMessage: "APIManager Degraded, 1/2 replicas available",
+		},
+		{
+			name:        "none available",
+			status:      &appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2},
+			generation:  1,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  progressingReason,
+			wantMessage: "APIManager Not Ready, 0/2 replicas available",
+		},
+		{
+			name:        "stale status",
+			status:      &appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			generation:  2,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  progressingReason,
+			wantMessage: "APIManager rollout pending, 2/2 replicas available",
+		},
+		{
+			name:        "rollout in progress",
+			status:      &appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2},
+			generation:  2,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  progressingReason,
+			wantMessage: "APIManager rollout in progress, 1/2 replicas updated, 2/2 replicas available",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder()
+			if tc.status != nil {
+				dep := &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, Generation: tc.generation},
+					Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+					Status:     *tc.status,
+				}
+				builder = builder.WithObjects(dep)
+			}
+
+			got := getDeploymentCondition(context.TODO(), builder.Build(), key, apiManagerReadyType, "APIManager", ctrl.Log)
+			assert.Equal(t, apiManagerReadyType, got.Type)
+			assert.Equal(t, tc.wantStatus, got.Status)
+			assert.Equal(t, tc.wantReason, got.Reason)
+			assert.Equal(t, tc.wantMessage, got.Message)
+		})
+	}
+}
+
+func TestGetNodeCondition(t *testing.T) {
+	cases := []struct {
+		name        string
+		status      *appsv1.DaemonSetStatus
+		generation  int64
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:        "not found",
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  notFoundReason,
+			wantMessage: "Node DaemonSet not found",
+		},
+		{
+			name:        "all ready",
+			status:      &appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 3},
+			generation:  1,
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  availableReason,
+			wantMessage: "Node Ready, 3/3 pods ready",
+		},
+		{
+			name:        "partially ready",
+			status:      &appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 2},
+			generation:  1,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  degradedReason,
+			wantMessage: "Node Degraded, 2/3 pods ready",
+		},
+		{
+			name:        "none ready",
+			status:      &appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3},
+			generation:  1,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  progressingReason,
+			wantMessage: "Node Not Ready, 0/3 pods ready",
+		},
+		{
+			name:        "no nodes scheduled",
+			status:      &appsv1.DaemonSetStatus{ObservedGeneration: 1},
+			generation:  1,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  progressingReason,
+			wantMessage: "Node Not Ready, 0/0 pods ready",
+		},
+		{
+			name:        "stale status",
+			status:      &appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 3},
+			generation:  2,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  progressingReason,
+			wantMessage: "Node rollout pending, 3/3 pods ready",
+		},
+		{
+			name:        "rollout in progress",
+			status:      &appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 1, NumberReady: 3},
+			generation:  2,
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  progressingReason,
+			wantMessage: "Node rollout in progress, 1/3 pods updated, 3/3 pods ready",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder()
+			if tc.status != nil {
+				ds := &appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "storageos-daemonset", Namespace: "some-ns", Generation: tc.generation},
+					Status:     *tc.status,
+				}
+				builder = builder.WithObjects(ds)
+			}
+
+			got := getNodeCondition(context.TODO(), builder.Build(), "some-ns", ctrl.Log)
+			assert.Equal(t, nodeReadyType, got.Type)
+			assert.Equal(t, tc.wantStatus, got.Status)
+			assert.Equal(t, tc.wantReason, got.Reason)
+			assert.Equal(t, tc.wantMessage, got.Message)
+		})
+	}
+}
+
+func TestGetControlPlaneMembers(t *testing.T) {
+	transitionTime := metav1.Unix(1000, 0)
+
+	newPod := func(name, nodeName, hostIP string, ready bool) *corev1.Pod {
+		readyStatus := corev1.ConditionFalse
+		if ready {
+			readyStatus = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "some-ns", Labels: getLabelsForControlPlane()},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+				Containers: []corev1.Container{
+					{Name: "init", Image: "storageos/init:v2.5.0"},
+					{Name: storageosContainer, Image: "storageos/node:v2.5.0"},
+				},
+			},
+			Status: corev1.PodStatus{
+				Phase:  corev1.PodRunning,
+				HostIP: hostIP,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: readyStatus, LastTransitionTime: transitionTime},
+				},
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "init", Ready: true, RestartCount: 1},
+					{Name: storageosContainer, Ready: ready, RestartCount: 2},
+				},
+			},
+		}
+	}
+
+	cl := fake.NewClientBuilder().WithObjects(
+		newPod("storageos-daemonset-a", "node-a", "10.0.0.1", true),
+		// Running but not ready.
+		newPod("storageos-daemonset-b", "node-b", "10.0.0.2", false),
+		// Scheduled without a host IP yet.
+		newPod("storageos-daemonset-c", "node-c", "", false),
+		// Not scheduled.
+		newPod("storageos-daemonset-d", "", "", false),
+		// Not a control-plane pod.
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"}},
+	).Build()
+
+	ms, err := getControlPlaneMembers(context.TODO(), cl, "some-ns", ctrl.Log)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"10.0.0.1"}, ms.Ready)
+	assert.Equal(t, []string{"10.0.0.2", "node-c", "storageos-daemonset-d"}, ms.Unready)
+	assert.Equal(t, "1/4", getReadyFromMembersStatus(ms))
+
+	assert.Len(t, ms.Nodes, 3)
+	assert.Equal(t, storageoscomv1.MemberStatus{
+		IP:                 "10.0.0.1",
+		PodName:            "storageos-daemonset-a",
+		Ready:              true,
+		ContainersReady:    "2/2",
+		RestartCount:       3,
+		Version:            "v2.5.0",
+		LastTransitionTime: &transitionTime,
+	}, ms.Nodes["node-a"])
+	assert.False(t, ms.Nodes["node-b"].Ready)
+	assert.Equal(t, "1/2", ms.Nodes["node-b"].ContainersReady)
+	assert.Equal(t, "", ms.Nodes["node-c"].IP)
+}
+
+func TestGetCRDFeatureCondition(t *testing.T) {
+	gv := schema.GroupVersion{Group: monitoringGroup, Version: "v1"}
+
+	cases := []struct {
+		name        string
+		kinds       []string
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:        "CRDs not found",
+			kinds:       []string{"ServiceMonitor"},
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  crdsNotFoundReason,
+			wantMessage: monitoringFeature.notFoundMessage,
+		},
+		{
+			name:        "CRDs found",
+			kinds:       monitoringKinds,
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  readyReason,
+			wantMessage: "Monitoring Ready",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv})
+			for _, kind := range tc.kinds {
+				mapper.Add(gv.WithKind(kind), meta.RESTScopeNamespace)
+			}
+			cl := &restMapperClient{Client: fake.NewClientBuilder().Build(), mapper: mapper}
+
+			got := getCRDFeatureCondition(cl, ctrl.Log, monitoringFeature)
+			assert.Equal(t, monitoringReadyType, got.Type)
+			assert.Equal(t, tc.wantStatus, got.Status)
+			assert.Equal(t, tc.wantReason, got.Reason)
+			assert.Equal(t, tc.wantMessage, got.Message)
+		})
+	}
+}
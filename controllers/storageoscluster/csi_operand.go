@@ -2,48 +2,96 @@ package storageoscluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/darkowlzz/operator-toolkit/declarative"
 	"github.com/darkowlzz/operator-toolkit/declarative/kubectl"
 	"github.com/darkowlzz/operator-toolkit/declarative/kustomize"
+	"github.com/darkowlzz/operator-toolkit/declarative/transform"
 	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
 	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kustomize/api/filesys"
 	kustomizetypes "sigs.k8s.io/kustomize/api/types"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+	"sigs.k8s.io/yaml"
 
 	storageoscomv1 "github.com/storageos/operator/apis/v1"
 	"github.com/storageos/operator/internal/image"
+	stransform "github.com/storageos/operator/internal/transform"
 )
 
 const (
 	// csiPackage contains the resource manifests for csi operand.
 	csiPackage = "csi"
 
+	// CSIDriverName is the name of the StorageOS CSI driver, used as the
+	// provisioner of StorageOS StorageClasses.
+	CSIDriverName = "csi.storageos.com"
+
+	// csiExternalAttacherContainer is the name of the csi external attacher
+	// container.
+	csiExternalAttacherContainer = "csi-external-attacher"
+
+	// csiExternalSnapshotterContainer is the name of the csi external
+	// snapshotter container.
+	csiExternalSnapshotterContainer = "csi-external-snapshotter"
+
+	// CSI host path volume names.
+	csiKubeletDirVolume         = "kubelet-dir"
+	csiPluginDirVolume          = "plugin-dir"
+	csiDeviceDirVolume          = "device-dir"
+	csiRegistrationDirVolume    = "registration-dir"
+	csiRegistrarSocketDirVolume = "registrar-socket-dir"
+
+	// csiDriverAttachRequiredField is the CSIDriver spec field that sets if
+	// the driver requires the attach operation.
+	csiDriverAttachRequiredField = "attachRequired"
+
+	// kubeletRegistrationPathArg is the csi driver registrar flag for the CSI
+	// socket path registered with the kubelet.
+	kubeletRegistrationPathArg = "--kubelet-registration-path"
+
+	// Supported CSI version and driver registration mode.
+	csiVersionV1                           = "v1"
+	csiDriverRegistrationModeNodeRegistrar = "NodeRegistrar"
+
 	// Kustomize image name for container image.
 	kImageCSIProvisioner = "csi-provisioner"
 	kImageCSIAttacher    = "csi-attacher"
 	kImageCSIResizer     = "csi-resizer"
+	kImageCSISnapshotter = "csi-snapshotter"
 
 	// Related image environment variable.
 	csiProvisionerEnvVar = "RELATED_IMAGE_CSIV1_EXTERNAL_PROVISIONER"
 	// TODO: Attacher env var has "V3" suffix for backwards compatibility.
 	// Remove the suffix when doing a breaking change.
-	csiAttacherEnvVar = "RELATED_IMAGE_CSIV1_EXTERNAL_ATTACHER_V3"
-	csiResizerEnvVar  = "RELATED_IMAGE_CSIV1_EXTERNAL_RESIZER"
+	csiAttacherEnvVar    = "RELATED_IMAGE_CSIV1_EXTERNAL_ATTACHER_V3"
+	csiResizerEnvVar     = "RELATED_IMAGE_CSIV1_EXTERNAL_RESIZER"
+	csiSnapshotterEnvVar = "RELATED_IMAGE_CSIV1_EXTERNAL_SNAPSHOTTER"
 )
 
 type CSIOperand struct {
 	name            string
 	client          client.Client
+	apiReader       client.Reader
 	requires        []string
 	requeueStrategy operand.RequeueStrategy
 	fs              filesys.FileSystem
 	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
 }
 
 var _ operand.Operand = &CSIOperand{}
@@ -78,29 +126,61 @@ func (c *CSIOperand) Ensure(ctx context.Context, obj client.Object, ownerRef met
 	ctx, span, _, _ := instrumentation.Start(ctx, "CSIOperand.Ensure")
 	defer span.End()
 
-	b, err := getCSIBuilder(c.fs, obj, c.kubectlClient)
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// The snapshotter requires the volume snapshot CRDs.
+	enableSnapshotter := false
+	if cluster.Spec.Snapshot.Enable {
+		served, err := isGroupKindsServed(c.client, volumeSnapshotGroup, volumeSnapshotKinds)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		enableSnapshotter = served
+	}
+
+	b, err := getCSIBuilder(c.fs, obj, c.kubectlClient, enableSnapshotter)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// The CSIDriver spec is immutable. Delete the existing CSIDriver if its
+	// spec has changed, to be recreated by apply.
+	desiredDriver, err := getCSIDriverFromManifest(b.Manifest())
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
+	if err := deleteChangedCSIDriver(ctx, c.client, desiredDriver); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
 
-	return nil, b.Apply(ctx)
+	// The CSI helper Deployment is used to tell if the resources were created or updated.
+	primary := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "storageos-csi-helper", Namespace: obj.GetNamespace()}}
+	return applyWithEvent(ctx, c.apiReader, c.recorder, b, obj, csiPackage, primary)
 }
 
 func (c *CSIOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
 	ctx, span, _, _ := instrumentation.Start(ctx, "CSIOperand.Delete")
 	defer span.End()
 
-	b, err := getCSIBuilder(c.fs, obj, c.kubectlClient)
+	b, err := getCSIBuilder(c.fs, obj, c.kubectlClient, false)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
 
-	return nil, b.Delete(ctx)
+	return deleteWithEvent(ctx, b, obj, csiPackage)
 }
 
-func getCSIBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+// getCSIBuilder returns the CSI helper resource builder. The external
+// snapshotter is added if enableSnapshotter is true.
+func getCSIBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient, enableSnapshotter bool) (*declarative.Builder, error) {
 	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
 	if !ok {
 		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
@@ -114,6 +194,7 @@ func getCSIBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.Kubectl
 		kImageCSIProvisioner: os.Getenv(csiProvisionerEnvVar),
 		kImageCSIAttacher:    os.Getenv(csiAttacherEnvVar),
 		kImageCSIResizer:     os.Getenv(csiResizerEnvVar),
+		kImageCSISnapshotter: os.Getenv(csiSnapshotterEnvVar),
 	}
 	images = append(images, image.GetKustomizeImageList(relatedImages)...)
 
@@ -123,32 +204,190 @@ func getCSIBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.Kubectl
 		kImageCSIProvisioner: cluster.Spec.Images.CSIExternalProvisionerContainer,
 		kImageCSIAttacher:    cluster.Spec.Images.CSIExternalAttacherContainer,
 		kImageCSIResizer:     cluster.Spec.Images.CSIExternalResizerContainer,
+		kImageCSISnapshotter: cluster.Spec.Images.CSIExternalSnapshotterContainer,
 	}
 	images = append(images, image.GetKustomizeImageList(namedImages)...)
 
+	if err := ValidateCSI(cluster); err != nil {
+		return nil, err
+	}
+
+	// Create deployment transforms.
+	deploymentTransforms := []transform.TransformFunc{}
+
+	// Set the CSI socket directory and the update strategy.
+	pluginDirTF := stransform.SetPodTemplateHostPathVolumeFunc(csiPluginDirVolume, cluster.GetCSIPluginDir(), hostPathTypePtr(corev1.HostPathDirectoryOrCreate))
+	strategyTF := stransform.SetScalarNodeStringValueFunc("type", cluster.GetCSIDeploymentStrategy(), "spec", "strategy")
+	deploymentTransforms = append(deploymentTransforms, pluginDirTF, strategyTF)
+
+	// The external attacher isn't needed if the driver doesn't require
+	// attachment.
+	requiresAttachment, err := cluster.GetCSIDriverRequiresAttachment()
+	if err != nil {
+		return nil, err
+	}
+	if !requiresAttachment {
+		deploymentTransforms = append(deploymentTransforms, stransform.RemovePodTemplateContainerFunc(csiExternalAttacherContainer))
+	}
+
+	// The external snapshotter is opt-in.
+	if !enableSnapshotter {
+		deploymentTransforms = append(deploymentTransforms, stransform.RemovePodTemplateContainerFunc(csiExternalSnapshotterContainer))
+	}
+
+	// Add pod placement transforms to spread the replicas.
+	antiAffinityTF := stransform.SetPodTemplatePodAntiAffinityFunc(getPodAntiAffinity(cluster, csiComponent))
+	topologySpreadTF := stransform.SetPodTemplateTopologySpreadConstraintsFunc(getTopologySpreadConstraints(cluster, csiComponent))
+
+	// Combine the default tolerations with the provided tolerations.
+	tolerationTF := stransform.SetPodTemplateTolerationFunc(getTolerations(cluster, getDefaultDeploymentTolerations()))
+
+	deploymentTransforms = append(deploymentTransforms, antiAffinityTF, topologySpreadTF, tolerationTF)
+
+	// Set the priority class of the distribution, if any.
+	deploymentTransforms = append(deploymentTransforms, getPriorityClassTransforms(cluster)...)
+
+	// Set if the driver requires the attach operation.
+	csiDriverTransforms := []transform.TransformFunc{
+		stransform.SetScalarNodeFunc(csiDriverAttachRequiredField, kyaml.NewScalarRNode(strconv.FormatBool(requiresAttachment)), "spec"),
+	}
+
+	// Add the common and pod labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
+
 	return declarative.NewBuilder(csiPackage, fs,
-		declarative.WithKustomizeMutationFunc([]kustomize.MutateFunc{
+		declarative.WithManifestTransform(transform.ManifestTransform{
+			"csi/deployment.yaml": deploymentTransforms,
+			"csi/csidriver.yaml":  csiDriverTransforms,
+		}),
+		declarative.WithKustomizeMutationFunc(append([]kustomize.MutateFunc{
 			kustomize.AddNamespace(cluster.GetNamespace()),
 			kustomize.AddImages(images),
-		}),
+		}, labelsMutateFuncs...)),
 		declarative.WithKubectlClient(kcl),
 	)
 }
 
+// ValidateCSI validates the CSI configuration of the cluster.
+func ValidateCSI(cluster *storageoscomv1.StorageOSCluster) error {
+	if v := cluster.GetCSIVersion(); v != csiVersionV1 {
+		return fmt.Errorf("csi: unsupported version %q, must be %q", v, csiVersionV1)
+	}
+	if mode := cluster.GetCSIDriverRegistrationMode(); mode != csiDriverRegistrationModeNodeRegistrar {
+		return fmt.Errorf("csi: unsupported driver registration mode %q, must be %q", mode, csiDriverRegistrationModeNodeRegistrar)
+	}
+	if _, err := cluster.GetCSIDriverRequiresAttachment(); err != nil {
+		return fmt.Errorf("csi: invalid driverRequiresAttachment %q: must be true or false", cluster.Spec.CSI.DriverRequiresAttachment)
+	}
+	switch strategy := appsv1.DeploymentStrategyType(cluster.GetCSIDeploymentStrategy()); strategy {
+	case appsv1.RollingUpdateDeploymentStrategyType, appsv1.RecreateDeploymentStrategyType:
+	default:
+		return fmt.Errorf("csi: unsupported deployment strategy %q, must be %q or %q", strategy, appsv1.RollingUpdateDeploymentStrategyType, appsv1.RecreateDeploymentStrategyType)
+	}
+
+	dirs := map[string]string{
+		"kubeletDir":              cluster.Spec.CSI.KubeletDir,
+		"pluginDir":               cluster.Spec.CSI.PluginDir,
+		"deviceDir":               cluster.Spec.CSI.DeviceDir,
+		"registrationDir":         cluster.Spec.CSI.RegistrationDir,
+		"registrarSocketDir":      cluster.Spec.CSI.RegistrarSocketDir,
+		"kubeletRegistrationPath": cluster.Spec.CSI.KubeletRegistrationPath,
+	}
+	for field, dir := range dirs {
+		if dir != "" && !path.IsAbs(dir) {
+			return fmt.Errorf("csi: %s %q must be an absolute path", field, dir)
+		}
+	}
+	return nil
+}
+
+// hostPathTypePtr returns a pointer to the given host path type.
+func hostPathTypePtr(t corev1.HostPathType) *corev1.HostPathType {
+	return &t
+}
+
+// getCSIDriverFromManifest returns the CSIDriver in the given manifest.
+func getCSIDriverFromManifest(manifest string) (*storagev1.CSIDriver, error) {
+	for _, doc := range strings.Split(manifest, "\n---\n") {
+		obj := &storagev1.CSIDriver{}
+		if err := yaml.Unmarshal([]byte(doc), obj); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal manifest: %w", err)
+		}
+		if obj.Kind == "CSIDriver" {
+			return obj, nil
+		}
+	}
+	return nil, errors.New("CSIDriver not found in manifest")
+}
+
+// csiDriverSpecChanged checks if the immutable spec fields of the current
+// CSIDriver differ from the desired CSIDriver. Fields that aren't set in the
+// current CSIDriver, e.g. dropped by an older API server, are ignored.
+func csiDriverSpecChanged(current, desired *storagev1.CSIDriver) bool {
+	boolChanged := func(cur, des *bool) bool {
+		return cur != nil && des != nil && *cur != *des
+	}
+	if boolChanged(current.Spec.AttachRequired, desired.Spec.AttachRequired) ||
+		boolChanged(current.Spec.PodInfoOnMount, desired.Spec.PodInfoOnMount) {
+		return true
+	}
+	if current.Spec.FSGroupPolicy != nil && desired.Spec.FSGroupPolicy != nil &&
+		*current.Spec.FSGroupPolicy != *desired.Spec.FSGroupPolicy {
+		return true
+	}
+	if len(current.Spec.VolumeLifecycleModes) > 0 && len(desired.Spec.VolumeLifecycleModes) > 0 &&
+		!reflect.DeepEqual(current.Spec.VolumeLifecycleModes, desired.Spec.VolumeLifecycleModes) {
+		return true
+	}
+	return false
+}
+
+// deleteChangedCSIDriver deletes the existing CSIDriver if its spec differs
+// from the desired CSIDriver.
+func deleteChangedCSIDriver(ctx context.Context, cl client.Client, desired *storagev1.CSIDriver) error {
+	ctx, span, _, log := instrumentation.Start(ctx, "deleteChangedCSIDriver")
+	defer span.End()
+
+	current := &storagev1.CSIDriver{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: desired.GetName()}, current); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get CSIDriver %q: %w", desired.GetName(), err)
+	}
+
+	if !csiDriverSpecChanged(current, desired) {
+		return nil
+	}
+
+	log.Info("CSIDriver spec changed, recreating", "name", desired.GetName())
+	if err := cl.Delete(ctx, current); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete CSIDriver %q: %w", desired.GetName(), err)
+	}
+	return nil
+}
+
 func NewCSIOperand(
 	name string,
 	client client.Client,
+	apiReader client.Reader,
 	requires []string,
 	requeueStrategy operand.RequeueStrategy,
 	fs filesys.FileSystem,
 	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
 ) *CSIOperand {
 	return &CSIOperand{
 		name:            name,
 		client:          client,
+		apiReader:       apiReader,
 		requires:        requires,
 		requeueStrategy: requeueStrategy,
 		fs:              fs,
 		kubectlClient:   kcl,
+		recorder:        recorder,
 	}
 }
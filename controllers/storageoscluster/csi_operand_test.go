@@ -0,0 +1,239 @@
+package storageoscluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestValidateCSI(t *testing.T) {
+	cases := []struct {
+		name    string
+		csi     storageoscomv1.StorageOSClusterCSI
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name: "valid configuration",
+			csi: storageoscomv1.StorageOSClusterCSI{
+				Version:                  "v1",
+				KubeletDir:               "/var/lib/k0s/kubelet",
+				DriverRegistrationMode:   "NodeRegistrar",
+				DriverRequiresAttachment: "false",
+				DeploymentStrategy:       "Recreate",
+			},
+		},
+		{
+			name:    "unsupported version",
+			csi:     storageoscomv1.StorageOSClusterCSI{Version: "v0"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported registration mode",
+			csi:     storageoscomv1.StorageOSClusterCSI{DriverRegistrationMode: "ClusterRegistrar"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid requires attachment",
+			csi:     storageoscomv1.StorageOSClusterCSI{DriverRequiresAttachment: "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported deployment strategy",
+			csi:     storageoscomv1.StorageOSClusterCSI{DeploymentStrategy: "statefulset"},
+			wantErr: true,
+		},
+		{
+			name:    "relative path",
+			csi:     storageoscomv1.StorageOSClusterCSI{PluginDir: "plugins/storageos"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{}
+			cluster.Spec.CSI = tc.csi
+			err := ValidateCSI(cluster)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestGetCSIBuilder(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name              string
+		csi               storageoscomv1.StorageOSClusterCSI
+		enableSnapshotter bool
+		wantPluginDir     string
+		wantAttach        bool
+		wantStrategy      appsv1.DeploymentStrategyType
+		wantContainers    []string
+	}{
+		{
+			name:           "defaults",
+			wantPluginDir:  "/var/lib/kubelet/plugins_registry/storageos",
+			wantAttach:     true,
+			wantStrategy:   appsv1.RollingUpdateDeploymentStrategyType,
+			wantContainers: []string{"csi-external-provisioner", "csi-external-attacher", "csi-external-resizer"},
+		},
+		{
+			name:              "snapshotter",
+			enableSnapshotter: true,
+			wantPluginDir:     "/var/lib/kubelet/plugins_registry/storageos",
+			wantAttach:        true,
+			wantStrategy:      appsv1.RollingUpdateDeploymentStrategyType,
+			wantContainers:    []string{"csi-external-provisioner", "csi-external-attacher", "csi-external-resizer", "csi-external-snapshotter"},
+		},
+		{
+			name: "custom kubelet dir without attachment",
+			csi: storageoscomv1.StorageOSClusterCSI{
+				KubeletDir:               "/var/lib/k0s/kubelet",
+				DriverRequiresAttachment: "false",
+				DeploymentStrategy:       "Recreate",
+			},
+			wantPluginDir:  "/var/lib/k0s/kubelet/plugins_registry/storageos",
+			wantStrategy:   appsv1.RecreateDeploymentStrategyType,
+			wantContainers: []string{"csi-external-provisioner", "csi-external-resizer"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+			}
+			cluster.Spec.CSI = tc.csi
+
+			b, err := getCSIBuilder(fs, cluster, nil, tc.enableSnapshotter)
+			assert.Nil(t, err)
+
+			var deployment *appsv1.Deployment
+			for _, doc := range strings.Split(b.Manifest(), "\n---\n") {
+				if strings.Contains(doc, "kind: Deployment") {
+					deployment = &appsv1.Deployment{}
+					assert.Nil(t, yaml.Unmarshal([]byte(doc), deployment))
+				}
+			}
+			assert.NotNil(t, deployment)
+
+			assert.Equal(t, tc.wantStrategy, deployment.Spec.Strategy.Type)
+
+			containers := []string{}
+			for _, c := range deployment.Spec.Template.Spec.Containers {
+				containers = append(containers, c.Name)
+			}
+			assert.Equal(t, tc.wantContainers, containers)
+
+			for _, vol := range deployment.Spec.Template.Spec.Volumes {
+				if vol.Name == csiPluginDirVolume {
+					assert.Equal(t, tc.wantPluginDir, vol.HostPath.Path)
+				}
+			}
+
+			driver, err := getCSIDriverFromManifest(b.Manifest())
+			assert.Nil(t, err)
+			assert.Equal(t, CSIDriverName, driver.Name)
+			assert.Equal(t, tc.wantAttach, *driver.Spec.AttachRequired)
+		})
+	}
+}
+
+func TestDeleteChangedCSIDriver(t *testing.T) {
+	boolPtr := func(b bool) *bool { return &b }
+	fsGroupPolicy := storagev1.ReadWriteOnceWithFSTypeFSGroupPolicy
+	fileFSGroupPolicy := storagev1.FileFSGroupPolicy
+
+	desired := &storagev1.CSIDriver{
+		ObjectMeta: metav1.ObjectMeta{Name: CSIDriverName},
+		Spec: storagev1.CSIDriverSpec{
+			AttachRequired:       boolPtr(false),
+			PodInfoOnMount:       boolPtr(true),
+			FSGroupPolicy:        &fsGroupPolicy,
+			VolumeLifecycleModes: []storagev1.VolumeLifecycleMode{storagev1.VolumeLifecyclePersistent},
+		},
+	}
+
+	cases := []struct {
+		name        string
+		current     *storagev1.CSIDriver
+		wantDeleted bool
+	}{
+		{
+			name: "not found",
+		},
+		{
+			name:    "unchanged",
+			current: desired.DeepCopy(),
+		},
+		{
+			name: "attachRequired changed",
+			current: func() *storagev1.CSIDriver {
+				d := desired.DeepCopy()
+				d.Spec.AttachRequired = boolPtr(true)
+				return d
+			}(),
+			wantDeleted: true,
+		},
+		{
+			name: "fsGroupPolicy changed",
+			current: func() *storagev1.CSIDriver {
+				d := desired.DeepCopy()
+				d.Spec.FSGroupPolicy = &fileFSGroupPolicy
+				return d
+			}(),
+			wantDeleted: true,
+		},
+		{
+			name: "fsGroupPolicy not supported",
+			current: func() *storagev1.CSIDriver {
+				d := desired.DeepCopy()
+				d.Spec.FSGroupPolicy = nil
+				return d
+			}(),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder()
+			if tc.current != nil {
+				builder = builder.WithObjects(tc.current)
+			}
+			cl := builder.Build()
+
+			err := deleteChangedCSIDriver(context.TODO(), cl, desired)
+			assert.Nil(t, err)
+
+			if tc.current == nil {
+				return
+			}
+			err = cl.Get(context.TODO(), client.ObjectKey{Name: CSIDriverName}, &storagev1.CSIDriver{})
+			assert.Equal(t, tc.wantDeleted, apierrors.IsNotFound(err))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package storageoscluster
+
+import (
+	"github.com/darkowlzz/operator-toolkit/declarative/transform"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/internal/distro"
+	stransform "github.com/storageos/operator/internal/transform"
+)
+
+// withDistroDefaults returns a copy of the cluster with the defaults of its
+// kubernetes distribution profile applied. The detected distribution is used
+// when the cluster doesn't specify one. Explicitly set cluster fields are
+// retained.
+func withDistroDefaults(cluster *storageoscomv1.StorageOSCluster, detectedDistro string) *storageoscomv1.StorageOSCluster {
+	c := cluster.DeepCopy()
+
+	if c.Spec.K8sDistro == "" {
+		c.Spec.K8sDistro = detectedDistro
+	}
+
+	profile, ok := distro.Get(c.Spec.K8sDistro)
+	if !ok {
+		return c
+	}
+
+	// The CSI paths are derived from the kubelet directory.
+	if c.Spec.CSI.KubeletDir == "" {
+		c.Spec.CSI.KubeletDir = profile.KubeletDir
+	}
+
+	return c
+}
+
+// getPriorityClassTransforms returns the pod template transforms to set the
+// priority class of the distribution profile of the cluster, if any.
+func getPriorityClassTransforms(cluster *storageoscomv1.StorageOSCluster) []transform.TransformFunc {
+	profile, ok := distro.Get(cluster.Spec.K8sDistro)
+	if !ok || profile.PriorityClassName == "" {
+		return nil
+	}
+	return []transform.TransformFunc{stransform.SetPodTemplatePriorityClassNameFunc(profile.PriorityClassName)}
+}
@@ -0,0 +1,94 @@
+package storageoscluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestWithDistroDefaults(t *testing.T) {
+	cases := []struct {
+		name           string
+		spec           storageoscomv1.StorageOSClusterSpec
+		detectedDistro string
+		wantDistro     string
+		wantKubeletDir string
+	}{
+		{
+			name:           "no distro",
+			wantKubeletDir: "/var/lib/kubelet",
+		},
+		{
+			name:           "distro profile",
+			spec:           storageoscomv1.StorageOSClusterSpec{K8sDistro: "microk8s"},
+			wantDistro:     "microk8s",
+			wantKubeletDir: "/var/snap/microk8s/common/var/lib/kubelet",
+		},
+		{
+			name:           "detected distro",
+			detectedDistro: "microk8s",
+			wantDistro:     "microk8s",
+			wantKubeletDir: "/var/snap/microk8s/common/var/lib/kubelet",
+		},
+		{
+			name:           "spec distro overrides detected distro",
+			spec:           storageoscomv1.StorageOSClusterSpec{K8sDistro: "openshift-4.8"},
+			detectedDistro: "microk8s",
+			wantDistro:     "openshift-4.8",
+			wantKubeletDir: "/var/lib/kubelet",
+		},
+		{
+			name: "spec overrides distro profile",
+			spec: storageoscomv1.StorageOSClusterSpec{
+				K8sDistro: "microk8s",
+				CSI:       storageoscomv1.StorageOSClusterCSI{KubeletDir: "/foo/kubelet"},
+			},
+			wantDistro:     "microk8s",
+			wantKubeletDir: "/foo/kubelet",
+		},
+		{
+			name:           "unknown distro",
+			spec:           storageoscomv1.StorageOSClusterSpec{K8sDistro: "kubeadm"},
+			wantDistro:     "kubeadm",
+			wantKubeletDir: "/var/lib/kubelet",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{Spec: tc.spec}
+			got := withDistroDefaults(cluster, tc.detectedDistro)
+
+			assert.Equal(t, tc.wantDistro, got.Spec.K8sDistro)
+			assert.Equal(t, tc.wantKubeletDir, got.GetKubeletDir())
+
+			// The original cluster must not be modified.
+			assert.Equal(t, tc.spec, cluster.Spec)
+		})
+	}
+}
+
+func TestGetPriorityClassTransforms(t *testing.T) {
+	cases := []struct {
+		name      string
+		k8sDistro string
+		want      int
+	}{
+		{name: "no distro"},
+		{name: "distro without priority class", k8sDistro: "eks"},
+		{name: "distro with priority class", k8sDistro: "gke", want: 1},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				Spec: storageoscomv1.StorageOSClusterSpec{K8sDistro: tc.k8sDistro},
+			}
+			assert.Len(t, getPriorityClassTransforms(cluster), tc.want)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package storageoscluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darkowlzz/operator-toolkit/declarative"
+	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reasons of the events recorded on the cluster object.
+const (
+	createdEventReason                = "Created"
+	updatedEventReason                = "Updated"
+	deletedEventReason                = "Deleted"
+	applyFailedEventReason            = "ApplyFailed"
+	controlPlaneConfiguredEventReason = "ControlPlaneConfigured"
+	secretMissingEventReason          = "SecretMissing"
+	sccMissingEventReason             = "SecurityContextConstraintsMissing"
+)
+
+// operandEvent is a ReconcilerEvent recorded on the cluster object.
+//
+// The operator only records the events returned by successful operand
+// operations. The events of failures and post ready actions are recorded by
+// the operands with their event recorder.
+type operandEvent struct {
+	object    runtime.Object
+	eventType string
+	reason    string
+	message   string
+}
+
+var _ eventv1.ReconcilerEvent = operandEvent{}
+
+// Record implements the ReconcilerEvent interface.
+func (e operandEvent) Record(recorder record.EventRecorder) {
+	recorder.Event(e.object, e.eventType, e.reason, e.message)
+}
+
+func newCreatedEvent(obj runtime.Object, component string) operandEvent {
+	return operandEvent{
+		object:    obj,
+		eventType: eventv1.K8sEventTypeNormal,
+		reason:    createdEventReason,
+		message:   fmt.Sprintf("Created %s resources", component),
+	}
+}
+
+func newUpdatedEvent(obj runtime.Object, component string) operandEvent {
+	return operandEvent{
+		object:    obj,
+		eventType: eventv1.K8sEventTypeNormal,
+		reason:    updatedEventReason,
+		message:   fmt.Sprintf("Updated %s resources", component),
+	}
+}
+
+func newDeletedEvent(obj runtime.Object, component string) operandEvent {
+	return operandEvent{
+		object:    obj,
+		eventType: eventv1.K8sEventTypeNormal,
+		reason:    deletedEventReason,
+		message:   fmt.Sprintf("Deleted %s resources", component),
+	}
+}
+
+func newApplyFailedEvent(obj runtime.Object, component string, err error) operandEvent {
+	return operandEvent{
+		object:    obj,
+		eventType: eventv1.K8sEventTypeWarning,
+		reason:    applyFailedEventReason,
+		message:   fmt.Sprintf("Failed to apply %s resources: %v", component, err),
+	}
+}
+
+func newControlPlaneConfiguredEvent(obj runtime.Object) operandEvent {
+	return operandEvent{
+		object:    obj,
+		eventType: eventv1.K8sEventTypeNormal,
+		reason:    controlPlaneConfiguredEventReason,
+		message:   "Updated the control-plane cluster configuration",
+	}
+}
+
+func newSecretMissingEvent(obj runtime.Object, secretName string) operandEvent {
+	return operandEvent{
+		object:    obj,
+		eventType: eventv1.K8sEventTypeWarning,
+		reason:    secretMissingEventReason,
+		message:   fmt.Sprintf("Secret %q with the storageos credentials not found", secretName),
+	}
+}
+
+func newSCCMissingEvent(obj runtime.Object, k8sDistro string) operandEvent {
+	return operandEvent{
+		object:    obj,
+		eventType: eventv1.K8sEventTypeWarning,
+		reason:    sccMissingEventReason,
+		message:   fmt.Sprintf("SecurityContextConstraints are required on %s but not served by the API server", k8sDistro),
+	}
+}
+
+// applyWithEvent applies the resources of a builder and returns an event
+// about the change of the primary object of the component. Created is
+// returned when the primary object didn't exist and Updated when its
+// generation changed. No change event is returned when primary is nil. An
+// ApplyFailed event is recorded if the apply fails. The primary object is read
+// with the given reader, which must not be cached. A cache may not have seen
+// the object before the apply, or the change after it.
+func applyWithEvent(ctx context.Context, cl client.Reader, recorder record.EventRecorder, b *declarative.Builder, cluster client.Object, component string, primary client.Object) (eventv1.ReconcilerEvent, error) {
+	var before client.Object
+	if primary != nil {
+		obj, err := getPrimaryObject(ctx, cl, primary)
+		if err != nil {
+			return nil, err
+		}
+		before = obj
+	}
+
+	if err := b.Apply(ctx); err != nil {
+		newApplyFailedEvent(cluster, component, err).Record(recorder)
+		return nil, err
+	}
+
+	if primary == nil {
+		return nil, nil
+	}
+	after, err := getPrimaryObject(ctx, cl, primary)
+	if err != nil {
+		return nil, err
+	}
+	return getChangeEvent(cluster, component, before, after), nil
+}
+
+// deleteWithEvent deletes the resources of a builder and returns a Deleted
+// event.
+func deleteWithEvent(ctx context.Context, b *declarative.Builder, cluster client.Object, component string) (eventv1.ReconcilerEvent, error) {
+	if err := b.Delete(ctx); err != nil {
+		return nil, err
+	}
+	return newDeletedEvent(cluster, component), nil
+}
+
+// deleteDisabledWithEvent deletes the resources of a disabled component and
+// returns a Deleted event. Nothing is deleted if the marker object of the
+// component doesn't exist, the resources were never applied or are already
+// deleted.
+func deleteDisabledWithEvent(ctx context.Context, cl client.Reader, b *declarative.Builder, cluster client.Object, component string, marker client.Object) (eventv1.ReconcilerEvent, error) {
+	obj, err := getPrimaryObject(ctx, cl, marker)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	return deleteWithEvent(ctx, b, cluster, component)
+}
+
+// getPrimaryObject returns a copy of the given object fetched from the API.
+// Nil is returned if the object doesn't exist.
+func getPrimaryObject(ctx context.Context, cl client.Reader, obj client.Object) (client.Object, error) {
+	o, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("failed to copy %v", obj)
+	}
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(obj), o); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+// getChangeEvent compares the primary object of a component before and after
+// an apply and returns an event describing the change, if any.
+func getChangeEvent(cluster client.Object, component string, before, after client.Object) eventv1.ReconcilerEvent {
+	switch {
+	case after == nil:
+		return nil
+	case before == nil:
+		return newCreatedEvent(cluster, component)
+	case before.GetGeneration() != after.GetGeneration():
+		return newUpdatedEvent(cluster, component)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package storageoscluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestOperandEventRecord(t *testing.T) {
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+	}
+
+	cases := []struct {
+		name  string
+		event eventv1.ReconcilerEvent
+		want  string
+	}{
+		{
+			name:  "created",
+			event: newCreatedEvent(cluster, nodePackage),
+			want:  "Normal Created Created node resources",
+		},
+		{
+			name:  "updated",
+			event: newUpdatedEvent(cluster, csiPackage),
+			want:  "Normal Updated Updated csi resources",
+		},
+		{
+			name:  "deleted",
+			event: newDeletedEvent(cluster, schedulerPackage),
+			want:  "Normal Deleted Deleted scheduler resources",
+		},
+		{
+			name:  "apply failed",
+			event: newApplyFailedEvent(cluster, apiManagerPackage, errors.New("some error")),
+			want:  "Warning ApplyFailed Failed to apply api-manager resources: some error",
+		},
+		{
+			name:  "control-plane configured",
+			event: newControlPlaneConfiguredEvent(cluster),
+			want:  "Normal ControlPlaneConfigured Updated the control-plane cluster configuration",
+		},
+		{
+			name:  "secret missing",
+			event: newSecretMissingEvent(cluster, "some-secret"),
+			want:  `Warning SecretMissing Secret "some-secret" with the storageos credentials not found`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			tc.event.Record(recorder)
+			assert.Equal(t, tc.want, <-recorder.Events)
+		})
+	}
+}
+
+func TestGetChangeEvent(t *testing.T) {
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+	}
+	newDS := func(generation int64) client.Object {
+		return &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "storageos-daemonset", Generation: generation}}
+	}
+
+	cases := []struct {
+		name   string
+		before client.Object
+		after  client.Object
+		want   eventv1.ReconcilerEvent
+	}{
+		{
+			name: "not found",
+		},
+		{
+			name:  "created",
+			after: newDS(1),
+			want:  newCreatedEvent(cluster, nodePackage),
+		},
+		{
+			name:   "updated",
+			before: newDS(1),
+			after:  newDS(2),
+			want:   newUpdatedEvent(cluster, nodePackage),
+		},
+		{
+			name:   "unchanged",
+			before: newDS(2),
+			after:  newDS(2),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, getChangeEvent(cluster, nodePackage, tc.before, tc.after))
+		})
+	}
+}
+
+func TestGetPrimaryObject(t *testing.T) {
+	existing := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "storageos-daemonset", Namespace: "some-ns", Generation: 3}}
+	cl := fake.NewClientBuilder().WithObjects(existing).Build()
+
+	primary := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "storageos-daemonset", Namespace: "some-ns"}}
+	got, err := getPrimaryObject(context.TODO(), cl, primary)
+	assert.Nil(t, err)
+	assert.NotNil(t, got)
+	assert.Equal(t, int64(3), got.GetGeneration())
+
+	// The given object must not be modified.
+	assert.Equal(t, int64(0), primary.GetGeneration())
+
+	missing := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"}}
+	got, err = getPrimaryObject(context.TODO(), cl, missing)
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+}
+
+// applyingKubectl is a KubectlClient that applies the StorageClass of a
+// manifest with a client, bumping its generation on update.
+type applyingKubectl struct {
+	client client.Client
+}
+
+func (k *applyingKubectl) Apply(ctx context.Context, namespace string, manifest string, validate bool, extraArgs ...string) error {
+	desired, err := getStorageClassFromManifest(manifest)
+	if err != nil {
+		return err
+	}
+	current := &storagev1.StorageClass{}
+	if err := k.client.Get(ctx, client.ObjectKeyFromObject(desired), current); err != nil {
+		desired.SetGeneration(1)
+		return k.client.Create(ctx, desired)
+	}
+	current.SetGeneration(current.GetGeneration() + 1)
+	return k.client.Update(ctx, current)
+}
+
+func (k *applyingKubectl) Delete(ctx context.Context, namespace string, manifest string, validate bool, extraArgs ...string) error {
+	return nil
+}
+
+func TestApplyWithEventLaggingCache(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+	}
+	cluster.Spec.StorageClassName = "fast"
+	cluster.Spec.SecretRefName = "some-secret"
+
+	existing := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "fast", Generation: 1},
+		Provisioner: CSIDriverName,
+	}
+
+	cases := []struct {
+		name     string
+		existing []client.Object
+		// lagging reads through a cache that doesn't see the apply.
+		lagging bool
+		want    eventv1.ReconcilerEvent
+	}{
+		{
+			name: "created",
+			want: newCreatedEvent(cluster, storageclassPackage),
+		},
+		{
+			name:     "updated",
+			existing: []client.Object{existing},
+			want:     newUpdatedEvent(cluster, storageclassPackage),
+		},
+		{
+			name:    "created, lagging cache",
+			lagging: true,
+		},
+		{
+			name:     "updated, lagging cache",
+			existing: []client.Object{existing},
+			lagging:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			apiServer := fake.NewClientBuilder().WithObjects(tc.existing...).Build()
+
+			// The reader is the API server, or a cache that is never
+			// updated.
+			var reader client.Reader = apiServer
+			if tc.lagging {
+				objs := []client.Object{}
+				for _, obj := range tc.existing {
+					objs = append(objs, obj.DeepCopyObject().(client.Object))
+				}
+				reader = fake.NewClientBuilder().WithObjects(objs...).Build()
+			}
+
+			b, err := getStorageClassBuilder(fs, cluster, &applyingKubectl{client: apiServer})
+			assert.Nil(t, err)
+
+			primary := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}}
+			got, err := applyWithEvent(context.TODO(), reader, record.NewFakeRecorder(1), b, cluster, storageclassPackage, primary)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// fakeKubectl is a KubectlClient that records the applied and deleted
+// manifests.
+type fakeKubectl struct {
+	applied []string
+	deleted []string
+}
+
+func (k *fakeKubectl) Apply(ctx context.Context, namespace string, manifest string, validate bool, extraArgs ...string) error {
+	k.applied = append(k.applied, manifest)
+	return nil
+}
+
+func (k *fakeKubectl) Delete(ctx context.Context, namespace string, manifest string, validate bool, extraArgs ...string) error {
+	k.deleted = append(k.deleted, manifest)
+	return nil
+}
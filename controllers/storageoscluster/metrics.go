@@ -0,0 +1,52 @@
+package storageoscluster
+
+import (
+	"context"
+	"time"
+
+	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/internal/metrics"
+)
+
+// instrumentedOperand is an operand that records the metrics of its Ensure
+// calls.
+type instrumentedOperand struct {
+	operand.Operand
+}
+
+// withMetrics wraps an operand to record the metrics of its Ensure calls.
+func withMetrics(op operand.Operand) operand.Operand {
+	return &instrumentedOperand{Operand: op}
+}
+
+func (o *instrumentedOperand) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+	start := time.Now()
+	event, err := o.Operand.Ensure(ctx, obj, ownerRef)
+	metrics.ObserveOperandEnsure(obj.GetName(), obj.GetNamespace(), o.Name(), start, err)
+	return event, err
+}
+
+// setClusterMetrics sets the phase and node metrics of a cluster.
+func setClusterMetrics(ctx context.Context, cl client.Client, cluster *storageoscomv1.StorageOSCluster, log logr.Logger) {
+	metrics.SetClusterPhase(cluster.GetName(), cluster.GetNamespace(), cluster.Status.Phase)
+
+	nodeDS := &appsv1.DaemonSet{}
+	nodeKey := client.ObjectKey{Name: "storageos-daemonset", Namespace: cluster.GetNamespace()}
+	if err := cl.Get(ctx, nodeKey, nodeDS); err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "failed to get node status for metrics")
+		return
+	}
+	metrics.SetClusterNodes(cluster.GetName(), cluster.GetNamespace(),
+		nodeDS.Status.NumberReady,
+		nodeDS.Status.DesiredNumberScheduled,
+		nodeDS.Status.UpdatedNumberScheduled,
+	)
+}
@@ -0,0 +1,187 @@
+package storageoscluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/darkowlzz/operator-toolkit/declarative"
+	"github.com/darkowlzz/operator-toolkit/declarative/kubectl"
+	"github.com/darkowlzz/operator-toolkit/declarative/kustomize"
+	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/api/filesys"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+const (
+	// monitoringPackage contains the resource manifests for monitoring
+	// operand.
+	monitoringPackage = "monitoring"
+
+	// monitoringGroup is the API group of the Prometheus Operator CRDs.
+	monitoringGroup = "monitoring.coreos.com"
+
+	// csiMetricsService is the name of the CSI helper metrics Service.
+	csiMetricsService = "storageos-csi-helper-metrics"
+)
+
+// monitoringKinds are the Prometheus Operator kinds created by the
+// monitoring operand.
+var monitoringKinds = []string{"ServiceMonitor", "PrometheusRule"}
+
+type MonitoringOperand struct {
+	name            string
+	client          client.Client
+	apiReader       client.Reader
+	requires        []string
+	requeueStrategy operand.RequeueStrategy
+	fs              filesys.FileSystem
+	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
+}
+
+var _ operand.Operand = &MonitoringOperand{}
+
+func (m *MonitoringOperand) Name() string                             { return m.name }
+func (m *MonitoringOperand) Requires() []string                       { return m.requires }
+func (m *MonitoringOperand) RequeueStrategy() operand.RequeueStrategy { return m.requeueStrategy }
+func (m *MonitoringOperand) ReadyCheck(ctx context.Context, obj client.Object) (bool, error) {
+	return true, nil
+}
+func (m *MonitoringOperand) PostReady(ctx context.Context, obj client.Object) error { return nil }
+
+func (m *MonitoringOperand) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+	ctx, span, _, log := instrumentation.Start(ctx, "MonitoringOperand.Ensure")
+	defer span.End()
+
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// The Prometheus Operator is installed separately. The cluster status
+	// reports the missing CRDs.
+	served, err := isGroupKindsServed(m.client, monitoringGroup, monitoringKinds)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Delete the resources applied before monitoring was disabled.
+	if !cluster.Spec.Monitoring.Enable {
+		log.V(4).Info("monitoring not enabled")
+		if !served {
+			return nil, nil
+		}
+		b, err := newMonitoringBuilder(m.fs, cluster, m.kubectlClient)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		// The CSI metrics Service marks the applied resources.
+		marker := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: csiMetricsService, Namespace: cluster.GetNamespace()}}
+		event, err := deleteDisabledWithEvent(ctx, m.apiReader, b, cluster, monitoringPackage, marker)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return event, err
+	}
+
+	if !served {
+		log.Info("prometheus operator CRDs not found, skipping monitoring resources creation")
+		return nil, nil
+	}
+
+	b, err := getMonitoringBuilder(m.fs, obj, m.kubectlClient)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return applyWithEvent(ctx, m.apiReader, m.recorder, b, obj, monitoringPackage, nil)
+}
+
+func (m *MonitoringOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
+	ctx, span, _, _ := instrumentation.Start(ctx, "MonitoringOperand.Delete")
+	defer span.End()
+
+	b, err := getMonitoringBuilder(m.fs, obj, m.kubectlClient)
+	if err != nil {
+		if errors.Is(err, noResourceErr) {
+			return nil, nil
+		}
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Nothing to delete if the Prometheus Operator CRDs aren't served.
+	served, err := isGroupKindsServed(m.client, monitoringGroup, monitoringKinds)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if !served {
+		return nil, nil
+	}
+
+	return deleteWithEvent(ctx, b, obj, monitoringPackage)
+}
+
+func getMonitoringBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// Skip if monitoring isn't enabled.
+	if !cluster.Spec.Monitoring.Enable {
+		return nil, noResourceErr
+	}
+
+	return newMonitoringBuilder(fs, cluster, kcl)
+}
+
+// newMonitoringBuilder returns the monitoring resource builder, whether
+// monitoring is enabled or not.
+func newMonitoringBuilder(fs filesys.FileSystem, cluster *storageoscomv1.StorageOSCluster, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+	// Add the common labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return declarative.NewBuilder(monitoringPackage, fs,
+		declarative.WithKustomizeMutationFunc(append([]kustomize.MutateFunc{
+			kustomize.AddNamespace(cluster.GetNamespace()),
+		}, labelsMutateFuncs...)),
+		declarative.WithKubectlClient(kcl),
+	)
+}
+
+func NewMonitoringOperand(
+	name string,
+	client client.Client,
+	apiReader client.Reader,
+	requires []string,
+	requeueStrategy operand.RequeueStrategy,
+	fs filesys.FileSystem,
+	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
+) *MonitoringOperand {
+	return &MonitoringOperand{
+		name:            name,
+		client:          client,
+		apiReader:       apiReader,
+		requires:        requires,
+		requeueStrategy: requeueStrategy,
+		fs:              fs,
+		kubectlClient:   kcl,
+		recorder:        recorder,
+	}
+}
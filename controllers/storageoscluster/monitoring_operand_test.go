@@ -0,0 +1,147 @@
+package storageoscluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestGetMonitoringBuilder(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	// serviceMonitor is a partial ServiceMonitor, to avoid importing the
+	// Prometheus Operator API.
+	type serviceMonitor struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Spec              struct {
+			Selector metav1.LabelSelector `json:"selector"`
+		} `json:"spec"`
+	}
+
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+	}
+
+	_, err = getMonitoringBuilder(fs, cluster, nil)
+	assert.ErrorIs(t, err, noResourceErr)
+
+	cluster.Spec.Monitoring.Enable = true
+	b, err := getMonitoringBuilder(fs, cluster, nil)
+	assert.Nil(t, err)
+
+	// Selectors of the ServiceMonitors, by name.
+	selectors := map[string]map[string]string{}
+	var csiService *corev1.Service
+	var rules int
+	for _, doc := range strings.Split(b.Manifest(), "\n---\n") {
+		switch {
+		case strings.Contains(doc, "kind: ServiceMonitor"):
+			sm := &serviceMonitor{}
+			assert.Nil(t, yaml.Unmarshal([]byte(doc), sm))
+			assert.Equal(t, "some-ns", sm.Namespace)
+			selectors[sm.Name] = sm.Spec.Selector.MatchLabels
+		case strings.Contains(doc, "kind: PrometheusRule"):
+			rules++
+			assert.Contains(t, doc, "namespace: some-ns")
+		case strings.Contains(doc, "kind: Service"):
+			csiService = &corev1.Service{}
+			assert.Nil(t, yaml.Unmarshal([]byte(doc), csiService))
+		}
+	}
+
+	assert.Equal(t, map[string]map[string]string{
+		"storageos-node":        {"app": "storageos", "app.kubernetes.io/component": "control-plane"},
+		"storageos-api-manager": {"app": "storageos", "app.kubernetes.io/component": "storageos-api-manager"},
+		"storageos-csi-helper":  {"app": "storageos", "app.kubernetes.io/component": "csi"},
+	}, selectors)
+	assert.Equal(t, 1, rules)
+
+	// The CSI metrics Service must select the CSI helper pods.
+	assert.NotNil(t, csiService)
+	assert.Equal(t, "some-ns", csiService.Namespace)
+	assert.Equal(t, map[string]string{"app": "storageos", "app.kubernetes.io/component": "csi"}, csiService.Spec.Selector)
+	assert.Len(t, csiService.Spec.Ports, 4)
+}
+
+func TestMonitoringOperandEnsure(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	gv := schema.GroupVersion{Group: monitoringGroup, Version: "v1"}
+	marker := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: csiMetricsService, Namespace: "some-ns"}}
+
+	cases := []struct {
+		name       string
+		enable     bool
+		served     bool
+		existing   []client.Object
+		wantApply  bool
+		wantDelete bool
+	}{
+		{
+			name:   "enabled, not served",
+			enable: true,
+		},
+		{
+			name:      "enabled",
+			enable:    true,
+			served:    true,
+			wantApply: true,
+		},
+		{
+			name:   "disabled",
+			served: true,
+		},
+		{
+			name:       "disabled after being applied",
+			served:     true,
+			existing:   []client.Object{marker},
+			wantDelete: true,
+		},
+		{
+			name:     "disabled after being applied, not served",
+			existing: []client.Object{marker},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv})
+			if tc.served {
+				for _, kind := range monitoringKinds {
+					mapper.Add(gv.WithKind(kind), meta.RESTScopeNamespace)
+				}
+			}
+			cl := &restMapperClient{Client: fake.NewClientBuilder().WithObjects(tc.existing...).Build(), mapper: mapper}
+			kcl := &fakeKubectl{}
+			op := NewMonitoringOperand(monitoringOpName, cl, cl, []string{}, operand.RequeueOnError, fs, kcl, record.NewFakeRecorder(10))
+
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+			}
+			cluster.Spec.Monitoring.Enable = tc.enable
+
+			_, err := op.Ensure(context.TODO(), cluster, metav1.OwnerReference{})
+			assert.Nil(t, err)
+
+			assert.Equal(t, tc.wantApply, len(kcl.applied) > 0)
+			assert.Equal(t, tc.wantDelete, len(kcl.deleted) > 0)
+		})
+	}
+}
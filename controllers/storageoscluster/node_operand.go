@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/darkowlzz/operator-toolkit/declarative"
 	"github.com/darkowlzz/operator-toolkit/declarative/kubectl"
@@ -15,7 +17,9 @@ import (
 	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kustomize/api/filesys"
 	kustomizetypes "sigs.k8s.io/kustomize/api/types"
@@ -36,6 +40,14 @@ const (
 	// initContainer is the name of the storageos init container.
 	initContainer = "storageos-init"
 
+	// csiDriverRegistrarContainer is the name of the csi driver registrar
+	// container.
+	csiDriverRegistrarContainer = "csi-driver-registrar"
+
+	// csiLivenessProbeContainer is the name of the csi liveness probe
+	// container.
+	csiLivenessProbeContainer = "csi-liveness-probe"
+
 	// storageosService is the default name of the storageos service.
 	storageosService = "storageos"
 
@@ -64,15 +76,60 @@ const (
 	nodeImageEnvVar             = "RELATED_IMAGE_STORAGEOS_NODE"
 	csiNodeDriverRegImageEnvVar = "RELATED_IMAGE_CSIV1_NODE_DRIVER_REGISTRAR"
 	csiLivenessProbeImageEnvVar = "RELATED_IMAGE_CSIV1_LIVENESS_PROBE"
+
+	// nfsImageEnvVar is the related image environment variable of the NFS
+	// server image used for shared filesystems.
+	nfsImageEnvVar = "RELATED_IMAGE_NFS"
 )
 
+// nodeConfigOwnedKeys are the node configmap keys set by the operator. These
+// are passed to the containers with envFrom and can't be overridden.
+var nodeConfigOwnedKeys = []string{
+	"ETCD_ENDPOINTS", "DISABLE_TELEMETRY", "DISABLE_VERSION_CHECK",
+	"DISABLE_CRASH_REPORTING", "CSI_ENDPOINT", "CSI_VERSION", "LOG_LEVEL",
+	"ETCD_TLS_CLIENT_CA", "ETCD_TLS_CLIENT_KEY", "ETCD_TLS_CLIENT_CERT",
+	"K8S_DISTRO", "DEVICE_DIR", "NFS_IMAGE",
+}
+
+// nodeContainerOwnedEnvVars are the env vars of the node containers that are
+// managed by the operator, keyed by container name.
+var nodeContainerOwnedEnvVars = map[string][]string{
+	storageosContainer:          append([]string{"HOSTNAME", "BOOTSTRAP_USERNAME", "BOOTSTRAP_PASSWORD", "ADVERTISE_IP"}, nodeConfigOwnedKeys...),
+	initContainer:               append([]string{"DAEMONSET_NAME", "DAEMONSET_NAMESPACE"}, nodeConfigOwnedKeys...),
+	csiDriverRegistrarContainer: {"ADDRESS", "KUBE_NODE_NAME"},
+	csiLivenessProbeContainer:   {"ADDRESS"},
+}
+
+// nodeContainerOwnedArgs are the flags of the node containers that are managed
+// by the operator, keyed by container name.
+var nodeContainerOwnedArgs = map[string][]string{
+	storageosContainer:          {},
+	initContainer:               {},
+	csiDriverRegistrarContainer: {"--csi-address", "--kubelet-registration-path"},
+	csiLivenessProbeContainer:   {"--csi-address"},
+}
+
+// nodeOwnedVolumes are the names of the node pod volumes managed by the
+// operator.
+var nodeOwnedVolumes = []string{
+	"kernel-modules", "fuse", "sys", "state", "config",
+	csiRegistrarSocketDirVolume, csiKubeletDirVolume, csiPluginDirVolume,
+	csiDeviceDirVolume, csiRegistrationDirVolume, tlsEtcdCertsVolume,
+	sharedDirVolume,
+}
+
 type NodeOperand struct {
 	name            string
 	client          client.Client
+	apiReader       client.Reader
 	requires        []string
 	requeueStrategy operand.RequeueStrategy
 	fs              filesys.FileSystem
 	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
+
+	// controlPlaneClient returns a control plane client of the cluster.
+	controlPlaneClient func(context.Context, client.Client, *storageoscomv1.StorageOSCluster) (*storageos.Client, error)
 }
 
 var _ operand.Operand = &NodeOperand{}
@@ -108,7 +165,29 @@ func (c *NodeOperand) Ensure(ctx context.Context, obj client.Object, ownerRef me
 	ctx, span, _, _ := instrumentation.Start(ctx, "NodeOperand.Ensure")
 	defer span.End()
 
-	b, err := getNodeBuilder(c.fs, obj, c.kubectlClient)
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// The node pods can't start without the storageos credentials.
+	secretKey := client.ObjectKey{Name: cluster.Spec.SecretRefName, Namespace: cluster.GetNamespace()}
+	if err := c.client.Get(ctx, secretKey, &corev1.Secret{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			newSecretMissingEvent(cluster, cluster.Spec.SecretRefName).Record(c.recorder)
+		}
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get storageos credentials: %w", err)
+	}
+
+	// Get the user provided node configuration, if any.
+	nodeConfig, err := getNodeConfigOverrides(ctx, c.client, cluster)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	b, err := getNodeBuilder(c.fs, obj, c.kubectlClient, nodeConfig)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -116,23 +195,27 @@ func (c *NodeOperand) Ensure(ctx context.Context, obj client.Object, ownerRef me
 
 	// TODO: Apply only at creation. Subsequent updates should only update
 	// individual properties.
-	return nil, b.Apply(ctx)
+	// The node DaemonSet is used to tell if the resources were created or updated.
+	primary := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "storageos-daemonset", Namespace: obj.GetNamespace()}}
+	return applyWithEvent(ctx, c.apiReader, c.recorder, b, obj, nodePackage, primary)
 }
 
 func (c *NodeOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
 	ctx, span, _, _ := instrumentation.Start(ctx, "NodeOperand.Delete")
 	defer span.End()
 
-	b, err := getNodeBuilder(c.fs, obj, c.kubectlClient)
+	// The node configuration isn't needed to delete the resources.
+	b, err := getNodeBuilder(c.fs, obj, c.kubectlClient, nil)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
 
-	return nil, b.Delete(ctx)
+	return deleteWithEvent(ctx, b, obj, nodePackage)
 }
 
-// PostReady performs actions after the control-plane is ready.
+// PostReady performs actions after the control-plane is ready. It returns an
+// error until the control plane is configured, for the operator to requeue.
 func (c *NodeOperand) PostReady(ctx context.Context, obj client.Object) error {
 	ctx, span, _, _ := instrumentation.Start(ctx, "NodeOperand.PostReady")
 	defer span.End()
@@ -143,14 +226,26 @@ func (c *NodeOperand) PostReady(ctx context.Context, obj client.Object) error {
 	}
 
 	// Get a control plane client and configure the cluster.
-	stosCl, err := getControlPlaneClient(ctx, c.client, cluster)
+	stosCl, err := c.controlPlaneClient(ctx, c.client, cluster)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			newSecretMissingEvent(cluster, cluster.Spec.SecretRefName).Record(c.recorder)
+		}
+		return err
+	}
+	updated, err := configureControlPlane(ctx, stosCl, cluster)
 	if err != nil {
 		return err
 	}
-	return configureControlPlane(ctx, stosCl, cluster)
+	if updated {
+		newControlPlaneConfiguredEvent(cluster).Record(c.recorder)
+	}
+	return nil
 }
 
-func getNodeBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+// getNodeBuilder returns a builder for the node package. nodeConfig is the
+// user provided node configuration, merged into the node configmap.
+func getNodeBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient, nodeConfig map[string]string) (*declarative.Builder, error) {
 	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
 	if !ok {
 		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
@@ -190,8 +285,21 @@ func getNodeBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.Kubect
 
 	daemonsetTransforms = append(daemonsetTransforms, usernameTF, passwordTF, initNamespaceTF)
 
-	// Create configmap transforms.
-	configmapTransforms := []transform.TransformFunc{
+	// Set the CSI host paths.
+	csiTransforms, err := getNodeCSITransforms(cluster)
+	if err != nil {
+		return nil, err
+	}
+	daemonsetTransforms = append(daemonsetTransforms, csiTransforms...)
+
+	// Create configmap transforms. The user provided node configuration is
+	// applied first, on top of the package defaults, followed by the
+	// configurations managed by the operator.
+	configmapTransforms, err := getNodeConfigTransforms(nodeConfig)
+	if err != nil {
+		return nil, err
+	}
+	configmapTransforms = append(configmapTransforms,
 		stransform.SetConfigMapData("ETCD_ENDPOINTS", cluster.Spec.KVBackend.Address),
 		stransform.SetConfigMapData("DISABLE_TELEMETRY", strconv.FormatBool(cluster.Spec.DisableTelemetry)),
 		// TODO: separte CR items for version check and crash reports.  Use
@@ -199,8 +307,9 @@ func getNodeBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.Kubect
 		stransform.SetConfigMapData("DISABLE_VERSION_CHECK", strconv.FormatBool(cluster.Spec.DisableTelemetry)),
 		stransform.SetConfigMapData("DISABLE_CRASH_REPORTING", strconv.FormatBool(cluster.Spec.DisableTelemetry)),
 		stransform.SetConfigMapData("CSI_ENDPOINT", cluster.GetCSIEndpoint()),
+		stransform.SetConfigMapData("CSI_VERSION", cluster.GetCSIVersion()),
 		stransform.SetConfigMapData("LOG_LEVEL", cluster.GetLogLevel()),
-	}
+	)
 
 	// If etcd TLS related values are set, mount the secret volume and set the
 	// etcd related configurations.
@@ -225,6 +334,12 @@ func getNodeBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.Kubect
 		configmapTransforms = append(configmapTransforms, stransform.SetConfigMapData("K8S_DISTRO", cluster.Spec.K8sDistro))
 	}
 
+	// Set the NFS server image of the shared filesystems, if enabled. The
+	// node default image is used if no image is provided.
+	if nfsImage := getNFSImage(cluster); cluster.Spec.SharedFilesystem.Enable && nfsImage != "" {
+		configmapTransforms = append(configmapTransforms, stransform.SetConfigMapData("NFS_IMAGE", nfsImage))
+	}
+
 	// If shared dir is set, mount the device as host path volume and set the
 	// configuration.
 	if cluster.Spec.SharedDir != "" {
@@ -244,19 +359,25 @@ func getNodeBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.Kubect
 		daemonsetTransforms = append(daemonsetTransforms, stransform.SetPodTemplateNodeSelectorTermsFunc(cluster.Spec.NodeSelectorTerms))
 	}
 
-	// Add the default tolerations.
-	tolerations := getDefaultTolerations()
-	// If tolerations are provided, append the tolerations.
-	if cluster.Spec.Tolerations != nil {
-		tolerations = append(tolerations, cluster.Spec.Tolerations...)
-	}
+	// Combine the default tolerations with the provided tolerations.
+	tolerations := getTolerations(cluster, getDefaultTolerations())
 	daemonsetTransforms = append(daemonsetTransforms, stransform.SetPodTemplateTolerationFunc(tolerations))
 
+	// Set the priority class of the distribution, if any.
+	daemonsetTransforms = append(daemonsetTransforms, getPriorityClassTransforms(cluster)...)
+
 	// If any resources are defined, set container resource requirements.
 	if cluster.Spec.Resources.Limits != nil || cluster.Spec.Resources.Requests != nil {
 		daemonsetTransforms = append(daemonsetTransforms, stransform.SetPodTemplateContainerResourceFunc(storageosContainer, cluster.Spec.Resources))
 	}
 
+	// Add the extra volumes and container configurations.
+	nodeContainerTransforms, err := getNodeContainerTransforms(cluster)
+	if err != nil {
+		return nil, err
+	}
+	daemonsetTransforms = append(daemonsetTransforms, nodeContainerTransforms...)
+
 	// Create service transforms.
 	serviceTransforms := []transform.TransformFunc{}
 	serviceName := storageosService
@@ -283,20 +404,220 @@ func getNodeBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.Kubect
 		serviceTransforms = append(serviceTransforms, transform.AddAnnotationsFunc(cluster.Spec.Service.Annotations))
 	}
 
+	// Add the common and pod labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
+
 	return declarative.NewBuilder(nodePackage, fs,
 		declarative.WithManifestTransform(transform.ManifestTransform{
 			"node/daemonset.yaml": daemonsetTransforms,
 			"node/configmap.yaml": configmapTransforms,
 			"node/service.yaml":   serviceTransforms,
 		}),
-		declarative.WithKustomizeMutationFunc([]kustomize.MutateFunc{
+		declarative.WithKustomizeMutationFunc(append([]kustomize.MutateFunc{
 			kustomize.AddNamespace(cluster.GetNamespace()),
 			kustomize.AddImages(images),
-		}),
+		}, labelsMutateFuncs...)),
 		declarative.WithKubectlClient(kcl),
 	)
 }
 
+// ValidateNodeConfig checks that the user provided node configuration doesn't
+// contain any of the keys managed by the operator.
+func ValidateNodeConfig(nodeConfig map[string]string) error {
+	for key := range nodeConfig {
+		if contains(nodeConfigOwnedKeys, key) {
+			return fmt.Errorf("node config key %q is managed by the operator", key)
+		}
+	}
+	return nil
+}
+
+// getNodeConfigOverrides returns the data of the user provided node
+// configmap referenced in the cluster spec.
+func getNodeConfigOverrides(ctx context.Context, kcl client.Client, cluster *storageoscomv1.StorageOSCluster) (map[string]string, error) {
+	if cluster.Spec.NodeConfigRefName == "" {
+		return nil, nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Name: cluster.Spec.NodeConfigRefName, Namespace: cluster.GetNamespace()}
+	if err := kcl.Get(ctx, key, cm); err != nil {
+		return nil, fmt.Errorf("failed to get node config %q: %w", cluster.Spec.NodeConfigRefName, err)
+	}
+	return cm.Data, nil
+}
+
+// getNodeConfigTransforms validates the user provided node configuration and
+// returns the transforms to set it in the node configmap.
+func getNodeConfigTransforms(nodeConfig map[string]string) ([]transform.TransformFunc, error) {
+	if err := ValidateNodeConfig(nodeConfig); err != nil {
+		return nil, err
+	}
+
+	// Sort the keys for a stable ordering of the transforms.
+	keys := make([]string, 0, len(nodeConfig))
+	for key := range nodeConfig {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	transforms := []transform.TransformFunc{}
+	for _, key := range keys {
+		transforms = append(transforms, stransform.SetConfigMapData(key, nodeConfig[key]))
+	}
+	return transforms, nil
+}
+
+// ValidateNodeContainers checks that the extra volumes and node container
+// configurations in the cluster spec don't override any of the operator
+// managed env vars, args and volumes.
+func ValidateNodeContainers(cluster *storageoscomv1.StorageOSCluster) error {
+	extraVolumes := map[string]bool{}
+	for _, vol := range cluster.Spec.NodeExtraVolumes {
+		if contains(nodeOwnedVolumes, vol.Name) {
+			return fmt.Errorf("nodeExtraVolumes: volume %q is managed by the operator", vol.Name)
+		}
+		if extraVolumes[vol.Name] {
+			return fmt.Errorf("nodeExtraVolumes: duplicate volume %q", vol.Name)
+		}
+		extraVolumes[vol.Name] = true
+	}
+
+	containers := map[string]bool{}
+	for _, c := range cluster.Spec.NodeContainers {
+		ownedEnvVars, ok := nodeContainerOwnedEnvVars[c.Name]
+		if !ok {
+			return fmt.Errorf("nodeContainers: unknown container %q", c.Name)
+		}
+		if containers[c.Name] {
+			return fmt.Errorf("nodeContainers: duplicate container %q", c.Name)
+		}
+		containers[c.Name] = true
+
+		for _, env := range c.ExtraEnv {
+			if contains(ownedEnvVars, env.Name) {
+				return fmt.Errorf("nodeContainers(%s): env var %q is managed by the operator", c.Name, env.Name)
+			}
+		}
+		for _, arg := range c.ExtraArgs {
+			flag := strings.SplitN(arg, "=", 2)[0]
+			if contains(nodeContainerOwnedArgs[c.Name], flag) {
+				return fmt.Errorf("nodeContainers(%s): arg %q is managed by the operator", c.Name, flag)
+			}
+		}
+		for _, mount := range c.ExtraVolumeMounts {
+			if !extraVolumes[mount.Name] {
+				return fmt.Errorf("nodeContainers(%s): volume mount %q must refer to a volume in nodeExtraVolumes", c.Name, mount.Name)
+			}
+			if mount.SubPath != "" || mount.SubPathExpr != "" {
+				return fmt.Errorf("nodeContainers(%s): volume mount %q: subPath and subPathExpr are not supported", c.Name, mount.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// getNodeCSITransforms returns the transforms to set the CSI host path
+// volumes, the kubelet and plugin directory mounts of the storageos container
+// and the socket path registered with the kubelet.
+func getNodeCSITransforms(cluster *storageoscomv1.StorageOSCluster) ([]transform.TransformFunc, error) {
+	if err := ValidateCSI(cluster); err != nil {
+		return nil, err
+	}
+
+	transforms := []transform.TransformFunc{
+		stransform.SetPodTemplateHostPathVolumeFunc(csiKubeletDirVolume, cluster.GetKubeletDir(), hostPathTypePtr(corev1.HostPathDirectory)),
+		stransform.SetPodTemplateHostPathVolumeFunc(csiPluginDirVolume, cluster.GetCSIPluginDir(), hostPathTypePtr(corev1.HostPathDirectoryOrCreate)),
+		stransform.SetPodTemplateHostPathVolumeFunc(csiDeviceDirVolume, cluster.GetCSIDeviceDir(), hostPathTypePtr(corev1.HostPathDirectory)),
+		stransform.SetPodTemplateHostPathVolumeFunc(csiRegistrationDirVolume, cluster.GetCSIRegistrationDir(), hostPathTypePtr(corev1.HostPathDirectory)),
+		stransform.SetPodTemplateHostPathVolumeFunc(csiRegistrarSocketDirVolume, cluster.GetCSIRegistrarSocketDir(), hostPathTypePtr(corev1.HostPathDirectoryOrCreate)),
+		// The kubelet directory is mounted at the same path as on the host
+		// for the mount paths to match the kubelet's.
+		stransform.SetPodTemplateVolumeMountFunc(storageosContainer, csiKubeletDirVolume, cluster.GetKubeletDir(), ""),
+		stransform.SetPodTemplateContainerArgFunc(csiDriverRegistrarContainer, kubeletRegistrationPathArg, cluster.GetCSIKubeletRegistrationPath()),
+	}
+
+	// The storageos container serves the CSI endpoint in the plugin
+	// directory. Mount the plugin directory at the same path as on the host,
+	// unless it's already mounted as part of the kubelet directory.
+	if !isSubPath(cluster.GetKubeletDir(), cluster.GetCSIPluginDir()) {
+		transforms = append(transforms, stransform.SetPodTemplateVolumeMountFunc(storageosContainer, csiPluginDirVolume, cluster.GetCSIPluginDir(), ""))
+	}
+	return transforms, nil
+}
+
+// isSubPath returns true if the given path is the base path or a path under
+// it.
+func isSubPath(base, p string) bool {
+	rel, err := filepath.Rel(base, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// getNodeContainerTransforms returns the transforms to add the extra volumes
+// and the extra env vars, args and volume mounts of the node containers.
+func getNodeContainerTransforms(cluster *storageoscomv1.StorageOSCluster) ([]transform.TransformFunc, error) {
+	if err := ValidateNodeContainers(cluster); err != nil {
+		return nil, err
+	}
+
+	transforms := []transform.TransformFunc{}
+
+	for _, vol := range cluster.Spec.NodeExtraVolumes {
+		transforms = append(transforms, stransform.SetPodTemplateVolumeSourceFunc(vol.Name, vol.VolumeSource))
+	}
+
+	for _, c := range cluster.Spec.NodeContainers {
+		isInit := c.Name == initContainer
+
+		for _, env := range c.ExtraEnv {
+			switch {
+			case env.ValueFrom != nil && isInit:
+				transforms = append(transforms, stransform.SetPodTemplateInitContainerEnvVarValueFromFunc(c.Name, env.Name, *env.ValueFrom))
+			case env.ValueFrom != nil:
+				transforms = append(transforms, stransform.SetPodTemplateContainerEnvVarValueFromFunc(c.Name, env.Name, *env.ValueFrom))
+			case isInit:
+				transforms = append(transforms, stransform.SetPodTemplateInitContainerEnvVarStringFunc(c.Name, env.Name, env.Value))
+			default:
+				transforms = append(transforms, stransform.SetPodTemplateContainerEnvVarStringFunc(c.Name, env.Name, env.Value))
+			}
+		}
+
+		if len(c.ExtraArgs) > 0 {
+			if isInit {
+				transforms = append(transforms, stransform.AppendPodTemplateInitContainerArgsFunc(c.Name, c.ExtraArgs))
+			} else {
+				transforms = append(transforms, stransform.AppendPodTemplateContainerArgsFunc(c.Name, c.ExtraArgs))
+			}
+		}
+
+		for _, mount := range c.ExtraVolumeMounts {
+			var propagation corev1.MountPropagationMode
+			if mount.MountPropagation != nil {
+				propagation = *mount.MountPropagation
+			}
+			if isInit {
+				transforms = append(transforms, stransform.SetPodTemplateInitContainerVolumeMountFunc(c.Name, mount.Name, mount.MountPath, propagation))
+				if mount.ReadOnly {
+					transforms = append(transforms, stransform.SetPodTemplateInitContainerVolumeMountReadOnlyFunc(c.Name, mount.Name))
+				}
+			} else {
+				transforms = append(transforms, stransform.SetPodTemplateVolumeMountFunc(c.Name, mount.Name, mount.MountPath, propagation))
+				if mount.ReadOnly {
+					transforms = append(transforms, stransform.SetPodTemplateVolumeMountReadOnlyFunc(c.Name, mount.Name))
+				}
+			}
+		}
+	}
+
+	return transforms, nil
+}
+
 // getControlPlaneClient creates an authenticated control plane client and
 // returns it.
 func getControlPlaneClient(ctx context.Context, kcl client.Client, cluster *storageoscomv1.StorageOSCluster) (*storageos.Client, error) {
@@ -328,15 +649,16 @@ func getControlPlaneClient(ctx context.Context, kcl client.Client, cluster *stor
 }
 
 // configureControlPlane takes the desired cluster configuration and
-// reconfigures the control-plane.
-func configureControlPlane(ctx context.Context, stosCl *storageos.Client, cluster *storageoscomv1.StorageOSCluster) error {
+// reconfigures the control-plane. It returns true if the configuration was
+// updated.
+func configureControlPlane(ctx context.Context, stosCl *storageos.Client, cluster *storageoscomv1.StorageOSCluster) (bool, error) {
 	ctx, span, _, log := instrumentation.Start(ctx, "configureControlPlane")
 	defer span.End()
 
 	// Get current cluster config.
 	currentConfig, err := stosCl.GetCluster(ctx)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Construct desired configuration.
@@ -354,26 +676,43 @@ func configureControlPlane(ctx context.Context, stosCl *storageos.Client, cluste
 
 	// Compare the current and desired configuration and update if necessary.
 	if currentConfig.IsEqual(desiredConfig) {
-		return nil
+		return false, nil
 	}
 	log.Info("current config doesn't match the desired config, applying update")
-	return stosCl.UpdateCluster(ctx, desiredConfig)
+	if err := stosCl.UpdateCluster(ctx, desiredConfig); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// getNFSImage returns the NFS server image of the cluster. The image in the
+// cluster spec overrides the operator related image.
+func getNFSImage(cluster *storageoscomv1.StorageOSCluster) string {
+	if cluster.Spec.Images.NFSContainer != "" {
+		return cluster.Spec.Images.NFSContainer
+	}
+	return os.Getenv(nfsImageEnvVar)
 }
 
 func NewNodeOperand(
 	name string,
 	client client.Client,
+	apiReader client.Reader,
 	requires []string,
 	requeueStrategy operand.RequeueStrategy,
 	fs filesys.FileSystem,
 	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
 ) *NodeOperand {
 	return &NodeOperand{
-		name:            name,
-		client:          client,
-		requires:        requires,
-		requeueStrategy: requeueStrategy,
-		fs:              fs,
-		kubectlClient:   kcl,
+		name:               name,
+		client:             client,
+		apiReader:          apiReader,
+		requires:           requires,
+		requeueStrategy:    requeueStrategy,
+		fs:                 fs,
+		kubectlClient:      kcl,
+		recorder:           recorder,
+		controlPlaneClient: getControlPlaneClient,
 	}
 }
@@ -3,11 +3,22 @@ package storageoscluster
 import (
 	"context"
 	"errors"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
 	"github.com/golang/mock/gomock"
 	api "github.com/storageos/go-api/v2"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
 
 	storageoscomv1 "github.com/storageos/operator/apis/v1"
 	"github.com/storageos/operator/internal/storageos"
@@ -116,12 +127,373 @@ func TestConfigureControlPlane(t *testing.T) {
 				mcp.EXPECT().UpdateCluster(gomock.Any(), updatedCluster, gomock.Any()).Times(1)
 			}
 
-			err := configureControlPlane(context.TODO(), stosCl, cluster)
+			updated, err := configureControlPlane(context.TODO(), stosCl, cluster)
 			if tc.wantErr {
 				assert.NotNil(t, err)
 			} else {
 				assert.Nil(t, err)
 			}
+			assert.Equal(t, tc.updatedCluster != nil, updated)
+		})
+	}
+}
+
+func TestValidateNodeContainers(t *testing.T) {
+	hostPathVolume := corev1.Volume{
+		Name: "extra",
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{Path: "/opt/extra"},
+		},
+	}
+
+	cases := []struct {
+		name         string
+		extraVolumes []corev1.Volume
+		containers   []storageoscomv1.NodeContainer
+		wantErr      bool
+	}{
+		{
+			name:         "valid configuration",
+			extraVolumes: []corev1.Volume{hostPathVolume},
+			containers: []storageoscomv1.NodeContainer{
+				{
+					Name:              storageosContainer,
+					ExtraEnv:          []corev1.EnvVar{{Name: "FOO", Value: "bar"}},
+					ExtraArgs:         []string{"--foo"},
+					ExtraVolumeMounts: []corev1.VolumeMount{{Name: "extra", MountPath: "/extra"}},
+				},
+				{
+					Name:      csiDriverRegistrarContainer,
+					ExtraArgs: []string{"--timeout=30s"},
+				},
+			},
+		},
+		{
+			name: "unknown container",
+			containers: []storageoscomv1.NodeContainer{
+				{Name: "foo"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate container",
+			containers: []storageoscomv1.NodeContainer{
+				{Name: storageosContainer},
+				{Name: storageosContainer},
+			},
+			wantErr: true,
+		},
+		{
+			name: "operator managed env var",
+			containers: []storageoscomv1.NodeContainer{
+				{
+					Name:     storageosContainer,
+					ExtraEnv: []corev1.EnvVar{{Name: "BOOTSTRAP_USERNAME", Value: "foo"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "operator managed config key",
+			containers: []storageoscomv1.NodeContainer{
+				{
+					Name:     initContainer,
+					ExtraEnv: []corev1.EnvVar{{Name: "ETCD_ENDPOINTS", Value: "foo:2379"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "operator managed arg",
+			containers: []storageoscomv1.NodeContainer{
+				{
+					Name:      csiDriverRegistrarContainer,
+					ExtraArgs: []string{"--csi-address=/foo/csi.sock"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "operator managed volume",
+			extraVolumes: []corev1.Volume{
+				{
+					Name:         "state",
+					VolumeSource: hostPathVolume.VolumeSource,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "mount unknown volume",
+			containers: []storageoscomv1.NodeContainer{
+				{
+					Name:              storageosContainer,
+					ExtraVolumeMounts: []corev1.VolumeMount{{Name: "state", MountPath: "/state"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name:         "mount with subpath",
+			extraVolumes: []corev1.Volume{hostPathVolume},
+			containers: []storageoscomv1.NodeContainer{
+				{
+					Name:              storageosContainer,
+					ExtraVolumeMounts: []corev1.VolumeMount{{Name: "extra", MountPath: "/extra", SubPath: "foo"}},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				Spec: storageoscomv1.StorageOSClusterSpec{
+					NodeExtraVolumes: tc.extraVolumes,
+					NodeContainers:   tc.containers,
+				},
+			}
+			err := ValidateNodeContainers(cluster)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateNodeConfig(t *testing.T) {
+	cases := []struct {
+		name       string
+		nodeConfig map[string]string
+		wantErr    bool
+	}{
+		{
+			name: "no config",
+		},
+		{
+			name: "valid config",
+			nodeConfig: map[string]string{
+				"LOG_FORMAT":                 "text",
+				"RECOMMENDED_MAX_PIDS_LIMIT": "65536",
+			},
+		},
+		{
+			name: "operator managed key",
+			nodeConfig: map[string]string{
+				"LOG_FORMAT":     "text",
+				"ETCD_ENDPOINTS": "http://foo:2379",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateNodeConfig(tc.nodeConfig)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestGetNodeBuilderCSIPluginDirMount(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name          string
+		csi           storageoscomv1.StorageOSClusterCSI
+		wantMountPath string
+	}{
+		{
+			name: "default plugin dir under kubelet dir",
+		},
+		{
+			name: "plugin dir outside kubelet dir",
+			csi: storageoscomv1.StorageOSClusterCSI{
+				KubeletDir: "/var/lib/kubelet",
+				PluginDir:  "/opt/csi/storageos",
+			},
+			wantMountPath: "/opt/csi/storageos",
+		},
+		{
+			name: "plugin dir sharing the kubelet dir prefix",
+			csi: storageoscomv1.StorageOSClusterCSI{
+				KubeletDir: "/var/lib/kubelet",
+				PluginDir:  "/var/lib/kubelet-plugins/storageos",
+			},
+			wantMountPath: "/var/lib/kubelet-plugins/storageos",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+			}
+			cluster.Spec.SecretRefName = "some-secret"
+			cluster.Spec.CSI = tc.csi
+
+			b, err := getNodeBuilder(fs, cluster, nil, nil)
+			assert.Nil(t, err)
+
+			var ds *appsv1.DaemonSet
+			for _, doc := range strings.Split(b.Manifest(), "\n---\n") {
+				if strings.Contains(doc, "kind: DaemonSet") {
+					ds = &appsv1.DaemonSet{}
+					assert.Nil(t, yaml.Unmarshal([]byte(doc), ds))
+				}
+			}
+			assert.NotNil(t, ds)
+
+			var mountPath string
+			for _, c := range ds.Spec.Template.Spec.Containers {
+				if c.Name != storageosContainer {
+					continue
+				}
+				for _, m := range c.VolumeMounts {
+					if m.Name == csiPluginDirVolume {
+						mountPath = m.MountPath
+					}
+				}
+			}
+			assert.Equal(t, tc.wantMountPath, mountPath)
+		})
+	}
+}
+
+func TestGetNodeBuilderNFSImage(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name          string
+		enable        bool
+		relatedImage  string
+		nfsContainer  string
+		wantNFSImage  string
+		wantNFSConfig bool
+	}{
+		{
+			name:         "disabled",
+			relatedImage: "foo/nfs:related",
+			nfsContainer: "foo/nfs:custom",
+		},
+		{
+			name:   "enabled without image",
+			enable: true,
+		},
+		{
+			name:          "enabled with related image",
+			enable:        true,
+			relatedImage:  "foo/nfs:related",
+			wantNFSImage:  "foo/nfs:related",
+			wantNFSConfig: true,
+		},
+		{
+			name:          "enabled with image override",
+			enable:        true,
+			relatedImage:  "foo/nfs:related",
+			nfsContainer:  "foo/nfs:custom",
+			wantNFSImage:  "foo/nfs:custom",
+			wantNFSConfig: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			os.Setenv(nfsImageEnvVar, tc.relatedImage)
+			defer os.Unsetenv(nfsImageEnvVar)
+
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+			}
+			cluster.Spec.SecretRefName = "some-secret"
+			cluster.Spec.SharedFilesystem.Enable = tc.enable
+			cluster.Spec.Images.NFSContainer = tc.nfsContainer
+
+			b, err := getNodeBuilder(fs, cluster, nil, nil)
+			assert.Nil(t, err)
+
+			var cm *corev1.ConfigMap
+			for _, doc := range strings.Split(b.Manifest(), "\n---\n") {
+				if strings.Contains(doc, "kind: ConfigMap") {
+					cm = &corev1.ConfigMap{}
+					assert.Nil(t, yaml.Unmarshal([]byte(doc), cm))
+				}
+			}
+			assert.NotNil(t, cm)
+
+			image, ok := cm.Data["NFS_IMAGE"]
+			assert.Equal(t, tc.wantNFSConfig, ok)
+			assert.Equal(t, tc.wantNFSImage, image)
+		})
+	}
+}
+
+func TestNodeOperandRequeuesUntilControlPlaneConfigured(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name          string
+		getClusterErr error
+		wantErr       bool
+	}{
+		{
+			name:          "control plane not configured",
+			getClusterErr: errors.New("connection refused"),
+			wantErr:       true,
+		},
+		{
+			name: "control plane configured",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mcp := mocks.NewMockControlPlane(mockCtrl)
+			mcp.EXPECT().GetCluster(gomock.Any()).Return(api.Cluster{}, nil, tc.getClusterErr).Times(1)
+
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+			}
+			cluster.Spec.SecretRefName = "some-secret"
+
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "some-secret", Namespace: "some-ns"}}
+			ds := &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "storageos-daemonset", Namespace: "some-ns"},
+				Status:     appsv1.DaemonSetStatus{NumberReady: 1},
+			}
+			cl := fake.NewClientBuilder().WithObjects(secret, ds).Build()
+
+			op := NewNodeOperand(nodeOpName, cl, cl, []string{}, operand.RequeueOnError, fs, &fakeKubectl{}, record.NewFakeRecorder(10))
+			op.controlPlaneClient = func(context.Context, client.Client, *storageoscomv1.StorageOSCluster) (*storageos.Client, error) {
+				return storageos.Mock(mcp), nil
+			}
+
+			// The operator requeues the reconcile on error, until the control
+			// plane is configured.
+			_, err := operand.CallEnsure(op)(context.TODO(), cluster, metav1.OwnerReference{})
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				assert.False(t, errors.Is(err, operand.ErrNotReady))
+			} else {
+				assert.Nil(t, err)
+			}
 		})
 	}
 }
@@ -0,0 +1,163 @@
+package storageoscluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darkowlzz/operator-toolkit/declarative"
+	"github.com/darkowlzz/operator-toolkit/declarative/kubectl"
+	"github.com/darkowlzz/operator-toolkit/declarative/transform"
+	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/api/filesys"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/internal/distro"
+	stransform "github.com/storageos/operator/internal/transform"
+)
+
+// openshiftPackage contains the resource manifests for openshift operand.
+const openshiftPackage = "openshift"
+
+// sccGroupKind is the GroupKind of OpenShift SecurityContextConstraints.
+var sccGroupKind = schema.GroupKind{Group: "security.openshift.io", Kind: "SecurityContextConstraints"}
+
+// sccServiceAccounts are the service accounts of the privileged pods that
+// are allowed to use the storageos SecurityContextConstraints.
+var sccServiceAccounts = []string{
+	"storageos-daemonset-sa",
+	"storageos-csi-helper-sa",
+	"storageos-scheduler-sa",
+}
+
+type OpenShiftOperand struct {
+	name            string
+	client          client.Client
+	requires        []string
+	requeueStrategy operand.RequeueStrategy
+	fs              filesys.FileSystem
+	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
+}
+
+var _ operand.Operand = &OpenShiftOperand{}
+
+func (oc *OpenShiftOperand) Name() string                             { return oc.name }
+func (oc *OpenShiftOperand) Requires() []string                       { return oc.requires }
+func (oc *OpenShiftOperand) RequeueStrategy() operand.RequeueStrategy { return oc.requeueStrategy }
+func (oc *OpenShiftOperand) ReadyCheck(ctx context.Context, obj client.Object) (bool, error) {
+	return true, nil
+}
+func (oc *OpenShiftOperand) PostReady(ctx context.Context, obj client.Object) error { return nil }
+
+func (oc *OpenShiftOperand) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+	ctx, span, _, log := instrumentation.Start(ctx, "OpenShiftOperand.Ensure")
+	defer span.End()
+
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// SecurityContextConstraints can only be created if the API server serves
+	// them. Warn if the distribution enforces them but they aren't served,
+	// the privileged pods won't be admitted.
+	served, err := isKindServed(oc.client, sccGroupKind)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if !served {
+		if profile, _ := distro.Get(cluster.Spec.K8sDistro); profile.SecurityContextConstraints {
+			log.Info("SecurityContextConstraints expected by the distribution but not served", "distro", cluster.Spec.K8sDistro)
+			newSCCMissingEvent(cluster, cluster.Spec.K8sDistro).Record(oc.recorder)
+			return nil, nil
+		}
+		log.V(4).Info("SecurityContextConstraints not supported")
+		return nil, nil
+	}
+
+	b, err := getOpenShiftBuilder(oc.fs, obj, oc.kubectlClient)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return applyWithEvent(ctx, oc.client, oc.recorder, b, obj, openshiftPackage, nil)
+}
+
+func (oc *OpenShiftOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
+	ctx, span, _, _ := instrumentation.Start(ctx, "OpenShiftOperand.Delete")
+	defer span.End()
+
+	// Nothing to delete if SecurityContextConstraints aren't served.
+	served, err := isKindServed(oc.client, sccGroupKind)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if !served {
+		return nil, nil
+	}
+
+	b, err := getOpenShiftBuilder(oc.fs, obj, oc.kubectlClient)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return deleteWithEvent(ctx, b, obj, openshiftPackage)
+}
+
+func getOpenShiftBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// Set the namespace of the service accounts allowed to use the
+	// SecurityContextConstraints. All the resources are cluster scoped, the
+	// namespace can't be added to the package.
+	roleBindingTransforms := []transform.TransformFunc{}
+	for _, sa := range sccServiceAccounts {
+		roleBindingTransforms = append(roleBindingTransforms, stransform.SetClusterRoleBindingSubjectNamespaceFunc(sa, cluster.GetNamespace()))
+	}
+
+	// Add the common labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return declarative.NewBuilder(openshiftPackage, fs,
+		declarative.WithManifestTransform(transform.ManifestTransform{
+			"openshift/scc-cluster-role-binding.yaml": roleBindingTransforms,
+		}),
+		declarative.WithKustomizeMutationFunc(labelsMutateFuncs),
+		declarative.WithKubectlClient(kcl),
+	)
+}
+
+func NewOpenShiftOperand(
+	name string,
+	client client.Client,
+	requires []string,
+	requeueStrategy operand.RequeueStrategy,
+	fs filesys.FileSystem,
+	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
+) *OpenShiftOperand {
+	return &OpenShiftOperand{
+		name:            name,
+		client:          client,
+		requires:        requires,
+		requeueStrategy: requeueStrategy,
+		fs:              fs,
+		kubectlClient:   kcl,
+		recorder:        recorder,
+	}
+}
@@ -0,0 +1,102 @@
+package storageoscluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/internal/distro"
+)
+
+// restMapperClient is a client with a REST mapper. The fake client doesn't
+// implement a REST mapper.
+type restMapperClient struct {
+	client.Client
+	mapper meta.RESTMapper
+}
+
+func (c *restMapperClient) RESTMapper() meta.RESTMapper { return c.mapper }
+
+func TestGetOpenShiftBuilder(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+	}
+
+	b, err := getOpenShiftBuilder(fs, cluster, nil)
+	assert.Nil(t, err)
+
+	var binding *rbacv1.ClusterRoleBinding
+	for _, doc := range strings.Split(b.Manifest(), "\n---\n") {
+		if strings.Contains(doc, "kind: ClusterRoleBinding") {
+			binding = &rbacv1.ClusterRoleBinding{}
+			assert.Nil(t, yaml.Unmarshal([]byte(doc), binding))
+		}
+	}
+	assert.NotNil(t, binding)
+
+	subjects := []string{}
+	for _, s := range binding.Subjects {
+		assert.Equal(t, "some-ns", s.Namespace)
+		subjects = append(subjects, s.Name)
+	}
+	assert.Equal(t, sccServiceAccounts, subjects)
+}
+
+func TestOpenShiftOperandNotServed(t *testing.T) {
+	// An empty REST mapper doesn't serve SecurityContextConstraints.
+	cl := &restMapperClient{
+		Client: fake.NewClientBuilder().Build(),
+		mapper: meta.NewDefaultRESTMapper(nil),
+	}
+	op := NewOpenShiftOperand(openshiftOpName, cl, []string{}, operand.RequeueOnError, nil, nil, nil)
+
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+	}
+
+	_, err := op.Ensure(context.TODO(), cluster, metav1.OwnerReference{})
+	assert.Nil(t, err)
+
+	_, err = op.Delete(context.TODO(), cluster)
+	assert.Nil(t, err)
+}
+
+func TestOpenShiftOperandNotServedOnOpenShift(t *testing.T) {
+	cl := &restMapperClient{
+		Client: fake.NewClientBuilder().Build(),
+		mapper: meta.NewDefaultRESTMapper(nil),
+	}
+	recorder := record.NewFakeRecorder(1)
+	op := NewOpenShiftOperand(openshiftOpName, cl, []string{}, operand.RequeueOnError, nil, nil, recorder)
+
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+		Spec:       storageoscomv1.StorageOSClusterSpec{K8sDistro: distro.OpenShift},
+	}
+
+	// The resources aren't applied, a warning is recorded instead.
+	_, err := op.Ensure(context.TODO(), cluster, metav1.OwnerReference{})
+	assert.Nil(t, err)
+
+	select {
+	case event := <-recorder.Events:
+		assert.Contains(t, event, sccMissingEventReason)
+	default:
+		t.Error("expected a SecurityContextConstraintsMissing event")
+	}
+}
@@ -4,17 +4,19 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
-	"time"
 
 	operatorv1 "github.com/darkowlzz/operator-toolkit/operator/v1"
 	"github.com/darkowlzz/operator-toolkit/operator/v1/executor"
 	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
 	"github.com/darkowlzz/operator-toolkit/telemetry"
+	"k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/kustomize/api/filesys"
 
 	"github.com/darkowlzz/operator-toolkit/declarative/kubectl"
+
+	configstorageoscomv1 "github.com/storageos/operator/apis/config.storageos.com/v1"
 )
 
 const instrumentationName = "github.com/storageos/operator/controllers/storageoscluster"
@@ -27,8 +29,33 @@ const (
 	storageclassOpName  = "storageclass-operand"
 	beforeInstallOpName = "before-install-operand"
 	afterInstallOpName  = "after-install-operand"
+	openshiftOpName     = "openshift-operand"
+	pspOpName           = "psp-operand"
+	snapshotOpName      = "snapshot-operand"
+	sharedFSOpName      = "shared-filesystem-operand"
+	monitoringOpName    = "monitoring-operand"
 )
 
+// operandNameSuffix is the suffix of the operand names, omitted in the
+// operator configuration.
+const operandNameSuffix = "-operand"
+
+// operandNames are the names of all the operands.
+var operandNames = []string{
+	apiManagerOpName,
+	csiOpName,
+	schedulerOpName,
+	nodeOpName,
+	storageclassOpName,
+	beforeInstallOpName,
+	afterInstallOpName,
+	openshiftOpName,
+	pspOpName,
+	snapshotOpName,
+	sharedFSOpName,
+	monitoringOpName,
+}
+
 var instrumentation *telemetry.Instrumentation
 
 func init() {
@@ -36,65 +63,135 @@ func init() {
 	instrumentation = telemetry.NewInstrumentation(instrumentationName)
 }
 
-func NewOperator(mgr ctrl.Manager, fs filesys.FileSystem, execStrategy executor.ExecutionStrategy) (*operatorv1.CompositeOperator, error) {
+func NewOperator(mgr ctrl.Manager, fs filesys.FileSystem, execStrategy executor.ExecutionStrategy, kubeVersion *version.Version, config configstorageoscomv1.OperatorConfig) (*operatorv1.CompositeOperator, error) {
 	_, span, _, log := instrumentation.Start(context.Background(), "storageoscluster.NewOperator")
 	defer span.End()
 
+	// Get the requeue strategies of the operands.
+	requeue, err := getRequeueStrategies(config.OperandRequeueStrategies)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
 	// Set up a kubectl client.
 	kcl := kubectl.New().IOStreams(genericclioptions.IOStreams{
 		Out:    ioutil.Discard,
 		ErrOut: ioutil.Discard,
 	})
 
+	// Set up an event recorder for the operands and the operator.
+	recorder := mgr.GetEventRecorderFor("storageoscluster-controller")
+
 	// Create operands with their relationships.
 	//
-	//      ┌────────────────┐        ┌───────────┐
-	//      │ before-install │        │ scheduler │
-	//      └───────┬────────┘        └───────────┘
-	//              │
-	//              ▼                 ┌──────────────┐
-	//          ┌────────┐            │ storageclass │
-	//    ┌─────┤  node  ├──┐         └──────────────┘
-	//    │     └────────┘  │
-	//    │                 │
-	//    │                 │
-	//    ▼                 ▼
-	// ┌─────┐       ┌─────────────┐
-	// │ csi │       │ api-manager │
-	// └──┬──┘       └─────────┬───┘
-	//    │                    │
-	//    │                    │
-	//    │                    │
-	//    │                    │
-	//    │ ┌───────────────┐  │
-	//    └►│ after-install │◄─┘
+	//      ┌────────────────┐   ┌───────────┐   ┌─────┐
+	//      │ before-install │   │ openshift │   │ psp │
+	//      └───────┬────────┘   └─────┬─────┘   └──┬──┘
+	//              │                  └─────┬──────┘
+	//              ▼                        ├─────────────┐
+	//          ┌────────┐                   │             ▼
+	//    ┌─────┤  node  │◄──────────────────┘       ┌───────────┐
+	//    │     └───┬────┘                           │ scheduler │
+	//    │         │                                └───────────┘
+	//    │         │
+	//    │         │                           ┌──────────────┐
+	//    │         │                           │ storageclass │
+	//    │         │                           └──────────────┘
+	//    │         │
+	//    │         │                           ┌──────────┐
+	//    │         │                           │ snapshot │
+	//    │         │                           └──────────┘
+	//    │         │
+	//    │         │                       ┌───────────────────┐
+	//    │         │                       │ shared-filesystem │
+	//    │         │                       └───────────────────┘
+	//    │         │
+	//    │         │                          ┌────────────┐
+	//    │         │                          │ monitoring │
+	//    ▼         ▼                          └────────────┘
+	// ┌─────┐  ┌─────────────┐
+	// │ csi │  │ api-manager │
+	// └──┬──┘  └──────┬──────┘
+	//    │            │
+	//    │            │
+	//    │ ┌──────────▼────┐
+	//    └►│ after-install │
 	//      └───────────────┘
 	//
-	// CSI and api-manager operands depend on Node. After-install operand
-	// depends on CSI and api-manager. Before-install, StorageClass and
-	// Scheduler operands are independent.
-	apiManagerOp := NewAPIManagerOperand(apiManagerOpName, mgr.GetClient(), []string{nodeOpName}, operand.RequeueOnError, fs, kcl)
-	csiOp := NewCSIOperand(csiOpName, mgr.GetClient(), []string{nodeOpName}, operand.RequeueOnError, fs, kcl)
-	schedulerOp := NewSchedulerOperand(schedulerOpName, mgr.GetClient(), []string{}, operand.RequeueOnError, fs, kcl)
-	nodeOp := NewNodeOperand(nodeOpName, mgr.GetClient(), []string{beforeInstallOpName}, operand.RequeueOnError, fs, kcl)
-	storageClassOp := NewStorageClassOperand(storageclassOpName, mgr.GetClient(), []string{}, operand.RequeueOnError, fs, kcl)
-	beforeInstallOp := NewBeforeInstallOperand(beforeInstallOpName, mgr.GetClient(), []string{}, operand.RequeueOnError, fs, kcl)
-	afterInstallOp := NewAfterInstallOperand(afterInstallOpName, mgr.GetClient(), []string{csiOpName, apiManagerOpName}, operand.RequeueOnError, fs, kcl)
-
-	// Create and return CompositeOperator.
+	// Node operand depends on before-install, openshift and psp. Scheduler
+	// operand depends on openshift and psp. CSI and api-manager operands
+	// depend on Node. After-install operand depends on CSI and api-manager.
+	// Before-install, openshift, psp, StorageClass, snapshot,
+	// shared-filesystem and monitoring operands are independent.
+	apiManagerOp := NewAPIManagerOperand(apiManagerOpName, mgr.GetClient(), mgr.GetAPIReader(), []string{nodeOpName}, requeue[apiManagerOpName], fs, kcl, recorder)
+	csiOp := NewCSIOperand(csiOpName, mgr.GetClient(), mgr.GetAPIReader(), []string{nodeOpName}, requeue[csiOpName], fs, kcl, recorder)
+	schedulerOp := NewSchedulerOperand(schedulerOpName, mgr.GetClient(), mgr.GetAPIReader(), []string{openshiftOpName, pspOpName}, requeue[schedulerOpName], fs, kcl, recorder, kubeVersion, config.ImageRegistry)
+	nodeOp := NewNodeOperand(nodeOpName, mgr.GetClient(), mgr.GetAPIReader(), []string{beforeInstallOpName, openshiftOpName, pspOpName}, requeue[nodeOpName], fs, kcl, recorder)
+	storageClassOp := NewStorageClassOperand(storageclassOpName, mgr.GetClient(), mgr.GetAPIReader(), []string{}, requeue[storageclassOpName], fs, kcl, recorder)
+	beforeInstallOp := NewBeforeInstallOperand(beforeInstallOpName, mgr.GetClient(), []string{}, requeue[beforeInstallOpName], fs, kcl, recorder)
+	afterInstallOp := NewAfterInstallOperand(afterInstallOpName, mgr.GetClient(), []string{csiOpName, apiManagerOpName}, requeue[afterInstallOpName], fs, kcl, recorder)
+	openshiftOp := NewOpenShiftOperand(openshiftOpName, mgr.GetClient(), []string{}, requeue[openshiftOpName], fs, kcl, recorder)
+	pspOp := NewPSPOperand(pspOpName, mgr.GetClient(), mgr.GetAPIReader(), []string{}, requeue[pspOpName], fs, kcl, recorder)
+	snapshotOp := NewSnapshotOperand(snapshotOpName, mgr.GetClient(), mgr.GetAPIReader(), []string{}, requeue[snapshotOpName], fs, kcl, recorder)
+	sharedFSOp := NewSharedFilesystemOperand(sharedFSOpName, mgr.GetClient(), mgr.GetAPIReader(), []string{}, requeue[sharedFSOpName], fs, kcl, recorder)
+	monitoringOp := NewMonitoringOperand(monitoringOpName, mgr.GetClient(), mgr.GetAPIReader(), []string{}, requeue[monitoringOpName], fs, kcl, recorder)
+
+	// Create and return CompositeOperator. The operands are wrapped to record
+	// their metrics.
 	return operatorv1.NewCompositeOperator(
-		operatorv1.WithEventRecorder(mgr.GetEventRecorderFor("storageoscluster-controller")),
+		operatorv1.WithEventRecorder(recorder),
 		operatorv1.WithExecutionStrategy(execStrategy),
-		operatorv1.WithOperands(apiManagerOp, csiOp, schedulerOp, nodeOp, storageClassOp, beforeInstallOp, afterInstallOp),
+		operatorv1.WithOperands(
+			withMetrics(apiManagerOp),
+			withMetrics(csiOp),
+			withMetrics(schedulerOp),
+			withMetrics(nodeOp),
+			withMetrics(storageClassOp),
+			withMetrics(beforeInstallOp),
+			withMetrics(afterInstallOp),
+			withMetrics(openshiftOp),
+			withMetrics(pspOp),
+			withMetrics(snapshotOp),
+			withMetrics(sharedFSOp),
+			withMetrics(monitoringOp),
+		),
 		operatorv1.WithInstrumentation(nil, nil, log),
-		operatorv1.WithRetryPeriod(5*time.Second), // TODO: Maybe make this configurable?
+		operatorv1.WithRetryPeriod(config.GetRetryPeriod()),
 	)
 }
 
-func NewStorageOSClusterController(mgr ctrl.Manager, fs filesys.FileSystem, execStrategy executor.ExecutionStrategy) (*StorageOSClusterController, error) {
-	operator, err := NewOperator(mgr, fs, execStrategy)
+func NewStorageOSClusterController(mgr ctrl.Manager, fs filesys.FileSystem, execStrategy executor.ExecutionStrategy, kubeVersion *version.Version, k8sDistro string, config configstorageoscomv1.OperatorConfig) (*StorageOSClusterController, error) {
+	operator, err := NewOperator(mgr, fs, execStrategy, kubeVersion, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a new operator: %w", err)
 	}
-	return &StorageOSClusterController{Operator: operator, Client: mgr.GetClient()}, nil
+	return &StorageOSClusterController{Operator: operator, Client: mgr.GetClient(), K8sDistro: k8sDistro}, nil
+}
+
+// getRequeueStrategies returns the requeue strategies of all the operands,
+// keyed by operand name, with the given overrides of the operator
+// configuration. The node operand requeues on error until the control plane
+// is configured, its PostReady fails until then.
+func getRequeueStrategies(overrides map[string]configstorageoscomv1.RequeueStrategy) (map[string]operand.RequeueStrategy, error) {
+	strategies := map[string]operand.RequeueStrategy{}
+	for _, name := range operandNames {
+		strategies[name] = operand.RequeueOnError
+	}
+
+	for key, strategy := range overrides {
+		name := key + operandNameSuffix
+		if _, ok := strategies[name]; !ok {
+			return nil, fmt.Errorf("unknown operand %q in requeue strategies", key)
+		}
+		switch strategy {
+		case configstorageoscomv1.RequeueOnError:
+			strategies[name] = operand.RequeueOnError
+		case configstorageoscomv1.RequeueAlways:
+			strategies[name] = operand.RequeueAlways
+		default:
+			return nil, fmt.Errorf("invalid requeue strategy %q of operand %q", strategy, key)
+		}
+	}
+	return strategies, nil
 }
@@ -0,0 +1,70 @@
+package storageoscluster
+
+import (
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	"github.com/stretchr/testify/assert"
+
+	configstorageoscomv1 "github.com/storageos/operator/apis/config.storageos.com/v1"
+)
+
+func TestGetRequeueStrategies(t *testing.T) {
+	cases := []struct {
+		name      string
+		overrides map[string]configstorageoscomv1.RequeueStrategy
+		want      map[string]operand.RequeueStrategy
+		wantErr   bool
+	}{
+		{
+			name: "defaults",
+			want: map[string]operand.RequeueStrategy{
+				nodeOpName: operand.RequeueOnError,
+				csiOpName:  operand.RequeueOnError,
+			},
+		},
+		{
+			name: "overrides",
+			overrides: map[string]configstorageoscomv1.RequeueStrategy{
+				"node": configstorageoscomv1.RequeueAlways,
+				"csi":  configstorageoscomv1.RequeueAlways,
+			},
+			want: map[string]operand.RequeueStrategy{
+				nodeOpName: operand.RequeueAlways,
+				csiOpName:  operand.RequeueAlways,
+			},
+		},
+		{
+			name: "unknown operand",
+			overrides: map[string]configstorageoscomv1.RequeueStrategy{
+				"foo": configstorageoscomv1.RequeueAlways,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid strategy",
+			overrides: map[string]configstorageoscomv1.RequeueStrategy{
+				"node": "Never",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := getRequeueStrategies(tc.overrides)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+
+			// All the operands have a strategy.
+			assert.Len(t, got, len(operandNames))
+			for name, strategy := range tc.want {
+				assert.Equal(t, strategy, got[name], name)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package storageoscluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darkowlzz/operator-toolkit/declarative"
+	"github.com/darkowlzz/operator-toolkit/declarative/kubectl"
+	"github.com/darkowlzz/operator-toolkit/declarative/transform"
+	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/api/filesys"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/internal/distro"
+	stransform "github.com/storageos/operator/internal/transform"
+)
+
+const (
+	// pspPackage contains the resource manifests for psp operand.
+	pspPackage = "psp"
+
+	// pspClusterRoleBinding is the name of the PodSecurityPolicy
+	// ClusterRoleBinding.
+	pspClusterRoleBinding = "storageos:psp"
+)
+
+// pspGroupKind is the GroupKind of PodSecurityPolicy.
+var pspGroupKind = schema.GroupKind{Group: "policy", Kind: "PodSecurityPolicy"}
+
+// pspServiceAccounts are the service accounts of the privileged pods that are
+// allowed to use the storageos PodSecurityPolicy, the same as the
+// SecurityContextConstraints.
+var pspServiceAccounts = sccServiceAccounts
+
+type PSPOperand struct {
+	name            string
+	client          client.Client
+	apiReader       client.Reader
+	requires        []string
+	requeueStrategy operand.RequeueStrategy
+	fs              filesys.FileSystem
+	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
+}
+
+var _ operand.Operand = &PSPOperand{}
+
+func (p *PSPOperand) Name() string                             { return p.name }
+func (p *PSPOperand) Requires() []string                       { return p.requires }
+func (p *PSPOperand) RequeueStrategy() operand.RequeueStrategy { return p.requeueStrategy }
+func (p *PSPOperand) ReadyCheck(ctx context.Context, obj client.Object) (bool, error) {
+	return true, nil
+}
+func (p *PSPOperand) PostReady(ctx context.Context, obj client.Object) error { return nil }
+
+func (p *PSPOperand) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+	ctx, span, _, log := instrumentation.Start(ctx, "PSPOperand.Ensure")
+	defer span.End()
+
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// PodSecurityPolicies were removed in kubernetes 1.25. They can only be
+	// created if the API server serves them.
+	served, err := isKindServed(p.client, pspGroupKind)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if !served {
+		log.V(4).Info("PodSecurityPolicies not supported")
+		return nil, nil
+	}
+
+	b, err := newPSPBuilder(p.fs, cluster, p.kubectlClient)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Delete the resources applied before the distribution changed.
+	if profile, _ := distro.Get(cluster.Spec.K8sDistro); !profile.PodSecurityPolicy {
+		log.V(4).Info("PodSecurityPolicy not expected by the distribution", "distro", cluster.Spec.K8sDistro)
+		// The PodSecurityPolicy ClusterRoleBinding marks the applied
+		// resources.
+		marker := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: pspClusterRoleBinding}}
+		event, err := deleteDisabledWithEvent(ctx, p.apiReader, b, cluster, pspPackage, marker)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return event, err
+	}
+
+	return applyWithEvent(ctx, p.apiReader, p.recorder, b, obj, pspPackage, nil)
+}
+
+func (p *PSPOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
+	ctx, span, _, _ := instrumentation.Start(ctx, "PSPOperand.Delete")
+	defer span.End()
+
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// Nothing to delete if PodSecurityPolicies aren't served.
+	served, err := isKindServed(p.client, pspGroupKind)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if !served {
+		return nil, nil
+	}
+
+	b, err := newPSPBuilder(p.fs, cluster, p.kubectlClient)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return deleteWithEvent(ctx, b, obj, pspPackage)
+}
+
+// newPSPBuilder returns the PodSecurityPolicy resource builder, whether the
+// distribution expects PodSecurityPolicies or not.
+func newPSPBuilder(fs filesys.FileSystem, cluster *storageoscomv1.StorageOSCluster, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+	// Set the namespace of the service accounts allowed to use the
+	// PodSecurityPolicy. All the resources are cluster scoped, the namespace
+	// can't be added to the package.
+	roleBindingTransforms := []transform.TransformFunc{}
+	for _, sa := range pspServiceAccounts {
+		roleBindingTransforms = append(roleBindingTransforms, stransform.SetClusterRoleBindingSubjectNamespaceFunc(sa, cluster.GetNamespace()))
+	}
+
+	// Add the common labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return declarative.NewBuilder(pspPackage, fs,
+		declarative.WithManifestTransform(transform.ManifestTransform{
+			"psp/psp-cluster-role-binding.yaml": roleBindingTransforms,
+		}),
+		declarative.WithKustomizeMutationFunc(labelsMutateFuncs),
+		declarative.WithKubectlClient(kcl),
+	)
+}
+
+func NewPSPOperand(
+	name string,
+	client client.Client,
+	apiReader client.Reader,
+	requires []string,
+	requeueStrategy operand.RequeueStrategy,
+	fs filesys.FileSystem,
+	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
+) *PSPOperand {
+	return &PSPOperand{
+		name:            name,
+		client:          client,
+		apiReader:       apiReader,
+		requires:        requires,
+		requeueStrategy: requeueStrategy,
+		fs:              fs,
+		kubectlClient:   kcl,
+		recorder:        recorder,
+	}
+}
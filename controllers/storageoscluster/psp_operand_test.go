@@ -0,0 +1,117 @@
+package storageoscluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/internal/distro"
+)
+
+func TestNewPSPBuilder(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+	}
+
+	b, err := newPSPBuilder(fs, cluster, nil)
+	assert.Nil(t, err)
+
+	assert.Contains(t, b.Manifest(), "kind: PodSecurityPolicy")
+
+	var binding *rbacv1.ClusterRoleBinding
+	for _, doc := range strings.Split(b.Manifest(), "\n---\n") {
+		if strings.Contains(doc, "kind: ClusterRoleBinding") {
+			binding = &rbacv1.ClusterRoleBinding{}
+			assert.Nil(t, yaml.Unmarshal([]byte(doc), binding))
+		}
+	}
+	assert.NotNil(t, binding)
+	assert.Equal(t, pspClusterRoleBinding, binding.Name)
+
+	subjects := []string{}
+	for _, s := range binding.Subjects {
+		assert.Equal(t, "some-ns", s.Namespace)
+		subjects = append(subjects, s.Name)
+	}
+	assert.Equal(t, pspServiceAccounts, subjects)
+}
+
+func TestPSPOperandEnsure(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	gv := schema.GroupVersion{Group: pspGroupKind.Group, Version: "v1beta1"}
+	marker := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: pspClusterRoleBinding}}
+
+	cases := []struct {
+		name       string
+		k8sDistro  string
+		served     bool
+		existing   []client.Object
+		wantApply  bool
+		wantDelete bool
+	}{
+		{
+			name:      "not served",
+			k8sDistro: distro.Rancher,
+		},
+		{
+			name:      "expected by the distribution",
+			k8sDistro: distro.Rancher,
+			served:    true,
+			wantApply: true,
+		},
+		{
+			name:      "not expected by the distribution",
+			k8sDistro: distro.EKS,
+			served:    true,
+		},
+		{
+			name:       "not expected after being applied",
+			k8sDistro:  distro.EKS,
+			served:     true,
+			existing:   []client.Object{marker},
+			wantDelete: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv})
+			if tc.served {
+				mapper.Add(gv.WithKind(pspGroupKind.Kind), meta.RESTScopeRoot)
+			}
+			cl := &restMapperClient{Client: fake.NewClientBuilder().WithObjects(tc.existing...).Build(), mapper: mapper}
+			kcl := &fakeKubectl{}
+			op := NewPSPOperand(pspOpName, cl, cl, []string{}, operand.RequeueOnError, fs, kcl, record.NewFakeRecorder(10))
+
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+				Spec:       storageoscomv1.StorageOSClusterSpec{K8sDistro: tc.k8sDistro},
+			}
+
+			_, err := op.Ensure(context.TODO(), cluster, metav1.OwnerReference{})
+			assert.Nil(t, err)
+
+			assert.Equal(t, tc.wantApply, len(kcl.applied) > 0)
+			assert.Equal(t, tc.wantDelete, len(kcl.deleted) > 0)
+		})
+	}
+}
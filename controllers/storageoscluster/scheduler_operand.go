@@ -13,33 +13,68 @@ import (
 	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kustomize/api/filesys"
 	kustomizetypes "sigs.k8s.io/kustomize/api/types"
 
 	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/internal/distro"
 	"github.com/storageos/operator/internal/image"
+	"github.com/storageos/operator/internal/storageos"
 	stransform "github.com/storageos/operator/internal/transform"
 )
 
 const (
-	// schedulerPackage contains the resource manifests for scheduler operand.
+	// schedulerPackage is the name prefix of the versioned packages
+	// containing the resource manifests for scheduler operand. Each package
+	// is suffixed with the KubeSchedulerConfiguration version it contains.
 	schedulerPackage = "scheduler"
 
+	// KubeSchedulerConfiguration versions.
+	schedulerConfigV1beta1 = "v1beta1"
+	schedulerConfigV1beta2 = "v1beta2"
+	schedulerConfigV1beta3 = "v1beta3"
+	schedulerConfigV1      = "v1"
+
+	// defaultKubeSchedulerImage is the kube-scheduler image, without tag, used
+	// when the kubernetes version is known.
+	defaultKubeSchedulerImage = "registry.k8s.io/kube-scheduler"
+
 	// Kustomize image name for container image.
 	kImageKubeScheduler = "kube-scheduler"
 
-	// Related image environment variable.
+	// Related image environment variable. This overwrites the default image
+	// based on the kubernetes version.
 	kubeSchedulerEnvVar = "RELATED_IMAGE_KUBE_SCHEDULER"
+
+	// SchedulerName is the name of the storageos scheduler profile.
+	SchedulerName = "storageos-scheduler"
+
+	// Scheduling framework extension points with weighted plugins.
+	extensionPointScore      = "score"
+	extensionPointMultiPoint = "multiPoint"
+)
+
+// Minimum kubernetes versions of the KubeSchedulerConfiguration versions.
+var (
+	minKubeVersionSchedulerConfigV1beta2 = version.MustParseGeneric("v1.22.0")
+	minKubeVersionSchedulerConfigV1beta3 = version.MustParseGeneric("v1.23.0")
+	minKubeVersionSchedulerConfigV1      = version.MustParseGeneric("v1.25.0")
 )
 
 type SchedulerOperand struct {
 	name            string
 	client          client.Client
+	apiReader       client.Reader
 	requires        []string
 	requeueStrategy operand.RequeueStrategy
 	fs              filesys.FileSystem
 	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
+	kubeVersion     *version.Version
+	imageRegistry   string
 }
 
 var _ operand.Operand = &SchedulerOperand{}
@@ -54,7 +89,7 @@ func (c *SchedulerOperand) ReadyCheck(ctx context.Context, obj client.Object) (b
 
 	// Get the deployment object and check status of the replicas.
 	schedulerDep := &appsv1.Deployment{}
-	key := client.ObjectKey{Name: "storageos-scheduler", Namespace: obj.GetNamespace()}
+	key := client.ObjectKey{Name: SchedulerName, Namespace: obj.GetNamespace()}
 	if err := c.client.Get(ctx, key, schedulerDep); err != nil {
 		return false, err
 	}
@@ -74,37 +109,149 @@ func (c *SchedulerOperand) Ensure(ctx context.Context, obj client.Object, ownerR
 	ctx, span, _, _ := instrumentation.Start(ctx, "SchedulerOperand.Ensure")
 	defer span.End()
 
-	b, err := getSchedulerBuilder(c.fs, obj, c.kubectlClient)
+	b, err := getSchedulerBuilder(c.fs, obj, c.kubectlClient, c.kubeVersion, c.imageRegistry)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
 
-	return nil, b.Apply(ctx)
+	// The scheduler Deployment is used to tell if the resources were created or updated.
+	primary := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "storageos-scheduler", Namespace: obj.GetNamespace()}}
+	return applyWithEvent(ctx, c.apiReader, c.recorder, b, obj, schedulerPackage, primary)
 }
 
 func (c *SchedulerOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
 	ctx, span, _, _ := instrumentation.Start(ctx, "SchedulerOperand.Delete")
 	defer span.End()
 
-	b, err := getSchedulerBuilder(c.fs, obj, c.kubectlClient)
+	b, err := getSchedulerBuilder(c.fs, obj, c.kubectlClient, c.kubeVersion, c.imageRegistry)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
 
-	return nil, b.Delete(ctx)
+	return deleteWithEvent(ctx, b, obj, schedulerPackage)
+}
+
+// getSchedulerConfigVersion returns the KubeSchedulerConfiguration version
+// supported by the given kubernetes version. The oldest version is returned
+// if the kubernetes version is unknown.
+func getSchedulerConfigVersion(kubeVersion *version.Version) string {
+	switch {
+	case kubeVersion == nil:
+		return schedulerConfigV1beta1
+	case kubeVersion.AtLeast(minKubeVersionSchedulerConfigV1):
+		return schedulerConfigV1
+	case kubeVersion.AtLeast(minKubeVersionSchedulerConfigV1beta3):
+		return schedulerConfigV1beta3
+	case kubeVersion.AtLeast(minKubeVersionSchedulerConfigV1beta2):
+		return schedulerConfigV1beta2
+	default:
+		return schedulerConfigV1beta1
+	}
+}
+
+// getSchedulerPackage returns the name of the scheduler package for the
+// given kubernetes version.
+func getSchedulerPackage(kubeVersion *version.Version) string {
+	return fmt.Sprintf("%s-%s", schedulerPackage, getSchedulerConfigVersion(kubeVersion))
+}
+
+// getDefaultKubeSchedulerImage returns the kube-scheduler image of the given
+// repository matching the given kubernetes version. An empty string is
+// returned if the kubernetes version is unknown, to use the default image of
+// the scheduler package.
+func getDefaultKubeSchedulerImage(repository string, kubeVersion *version.Version) string {
+	if kubeVersion == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:v%d.%d.%d", repository,
+		kubeVersion.Major(), kubeVersion.Minor(), kubeVersion.Patch())
+}
+
+// ValidateScheduler validates the scheduler configuration of the cluster.
+func ValidateScheduler(cluster *storageoscomv1.StorageOSCluster) error {
+	extensionPoints := map[string]bool{}
+	for _, ps := range cluster.Spec.Scheduler.Plugins {
+		if extensionPoints[ps.ExtensionPoint] {
+			return fmt.Errorf("scheduler: duplicate extension point %q", ps.ExtensionPoint)
+		}
+		extensionPoints[ps.ExtensionPoint] = true
+
+		for _, p := range ps.Enabled {
+			if p.Name == "" {
+				return fmt.Errorf("scheduler(%s): plugin name must not be empty", ps.ExtensionPoint)
+			}
+			if p.Weight != nil && ps.ExtensionPoint != extensionPointScore && ps.ExtensionPoint != extensionPointMultiPoint {
+				return fmt.Errorf("scheduler(%s): plugin %q weight is only supported for %s and %s plugins", ps.ExtensionPoint, p.Name, extensionPointScore, extensionPointMultiPoint)
+			}
+		}
+		for _, name := range ps.Disabled {
+			if name == "" {
+				return fmt.Errorf("scheduler(%s): plugin name must not be empty", ps.ExtensionPoint)
+			}
+		}
+	}
+	return nil
+}
+
+// getSchedulerProfileTransforms returns the transforms to configure the
+// storageos-scheduler profile for the given KubeSchedulerConfiguration
+// version.
+func getSchedulerProfileTransforms(cluster *storageoscomv1.StorageOSCluster, configVersion string) ([]transform.TransformFunc, error) {
+	if err := ValidateScheduler(cluster); err != nil {
+		return nil, err
+	}
+
+	transforms := []transform.TransformFunc{}
+
+	if cluster.Spec.Scheduler.PercentageOfNodesToScore != nil {
+		transforms = append(transforms, stransform.SetKubeSchedulerPercentageOfNodesToScoreFunc(*cluster.Spec.Scheduler.PercentageOfNodesToScore))
+	}
+
+	for _, ps := range cluster.Spec.Scheduler.Plugins {
+		// multiPoint was introduced in v1beta3.
+		if ps.ExtensionPoint == extensionPointMultiPoint &&
+			(configVersion == schedulerConfigV1beta1 || configVersion == schedulerConfigV1beta2) {
+			return nil, fmt.Errorf("scheduler: extension point %q is not supported by KubeSchedulerConfiguration %s", ps.ExtensionPoint, configVersion)
+		}
+
+		pluginSet := stransform.KubeSchedulerPluginSet{}
+		for _, p := range ps.Enabled {
+			pluginSet.Enabled = append(pluginSet.Enabled, stransform.KubeSchedulerPlugin{Name: p.Name, Weight: p.Weight})
+		}
+		for _, name := range ps.Disabled {
+			pluginSet.Disabled = append(pluginSet.Disabled, stransform.KubeSchedulerPlugin{Name: name})
+		}
+		transforms = append(transforms, stransform.SetKubeSchedulerProfilePluginsFunc(SchedulerName, ps.ExtensionPoint, pluginSet))
+	}
+
+	return transforms, nil
 }
 
-func getSchedulerBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+func getSchedulerBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient, kubeVersion *version.Version, imageRegistry string) (*declarative.Builder, error) {
 	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
 	if !ok {
 		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
 	}
 
+	pkg := getSchedulerPackage(kubeVersion)
+
 	// Get image name.
 	images := []kustomizetypes.Image{}
 
+	// Use the kube-scheduler image matching the kubernetes version, from the
+	// repository of the distribution, if any. The operator image registry
+	// overrides its registry, like the related images.
+	repository := defaultKubeSchedulerImage
+	if profile, ok := distro.Get(cluster.Spec.K8sDistro); ok && profile.KubeSchedulerRepository != "" {
+		repository = profile.KubeSchedulerRepository
+	}
+	defaultImages := image.NamedImages{
+		kImageKubeScheduler: image.WithRegistry(getDefaultKubeSchedulerImage(repository, kubeVersion), imageRegistry),
+	}
+	images = append(images, image.GetKustomizeImageList(defaultImages)...)
+
 	// Check environment variables for related images.
 	relatedImages := image.NamedImages{
 		kImageKubeScheduler: os.Getenv(kubeSchedulerEnvVar),
@@ -126,14 +273,48 @@ func getSchedulerBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.K
 
 	configTransforms = append(configTransforms, rnsTF)
 
-	return declarative.NewBuilder(schedulerPackage, fs,
+	// Remove the extender in profile mode, else point the extender to the
+	// storageos service, using the namespace qualified service name. The
+	// extender uses the scheme of the storageos API served by the service.
+	if cluster.Spec.Scheduler.Mode == storageoscomv1.SchedulerModeProfile {
+		configTransforms = append(configTransforms, stransform.RemoveKubeSchedulerExtendersFunc())
+	} else {
+		extenderHost := fmt.Sprintf("%s.%s.svc", cluster.GetServiceName(), cluster.GetNamespace())
+		configTransforms = append(configTransforms, stransform.SetKubeSchedulerExtenderURLPrefixFunc(extenderHost, cluster.GetServicePort(), storageos.DefaultScheme == "https"))
+	}
+
+	profileTransforms, err := getSchedulerProfileTransforms(cluster, getSchedulerConfigVersion(kubeVersion))
+	if err != nil {
+		return nil, err
+	}
+	configTransforms = append(configTransforms, profileTransforms...)
+
+	// Create deployment transforms.
+	deploymentTransforms := []transform.TransformFunc{}
+
+	// Combine the default tolerations with the provided tolerations.
+	tolerationTF := stransform.SetPodTemplateTolerationFunc(getTolerations(cluster, getDefaultDeploymentTolerations()))
+
+	deploymentTransforms = append(deploymentTransforms, tolerationTF)
+
+	// Set the priority class of the distribution, if any.
+	deploymentTransforms = append(deploymentTransforms, getPriorityClassTransforms(cluster)...)
+
+	// Add the common and pod labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return declarative.NewBuilder(pkg, fs,
 		declarative.WithManifestTransform(transform.ManifestTransform{
-			"scheduler/config.yaml": configTransforms,
+			pkg + "/config.yaml":     configTransforms,
+			pkg + "/deployment.yaml": deploymentTransforms,
 		}),
-		declarative.WithKustomizeMutationFunc([]kustomize.MutateFunc{
+		declarative.WithKustomizeMutationFunc(append([]kustomize.MutateFunc{
 			kustomize.AddNamespace(cluster.GetNamespace()),
 			kustomize.AddImages(images),
-		}),
+		}, labelsMutateFuncs...)),
 		declarative.WithKubectlClient(kcl),
 	)
 }
@@ -141,17 +322,25 @@ func getSchedulerBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.K
 func NewSchedulerOperand(
 	name string,
 	client client.Client,
+	apiReader client.Reader,
 	requires []string,
 	requeueStrategy operand.RequeueStrategy,
 	fs filesys.FileSystem,
 	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
+	kubeVersion *version.Version,
+	imageRegistry string,
 ) *SchedulerOperand {
 	return &SchedulerOperand{
 		name:            name,
 		client:          client,
+		apiReader:       apiReader,
 		requires:        requires,
 		requeueStrategy: requeueStrategy,
 		fs:              fs,
 		kubectlClient:   kcl,
+		recorder:        recorder,
+		kubeVersion:     kubeVersion,
+		imageRegistry:   imageRegistry,
 	}
 }
@@ -0,0 +1,322 @@
+package storageoscluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/yaml"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+// Fields of a KubeSchedulerConfiguration used by the operator, per version.
+// These are checked against the rendered configuration to catch fields that
+// kube-scheduler would reject.
+var (
+	schedulerConfigCommonFields = []string{
+		"apiVersion", "kind", "parallelism", "leaderElection",
+		"clientConnection", "enableProfiling", "enableContentionProfiling",
+		"percentageOfNodesToScore", "podInitialBackoffSeconds",
+		"podMaxBackoffSeconds", "profiles", "extenders",
+	}
+	schedulerConfigFields = map[string][]string{
+		// healthzBindAddress and metricsBindAddress were removed in v1beta3.
+		schedulerConfigV1beta1: append([]string{"healthzBindAddress", "metricsBindAddress"}, schedulerConfigCommonFields...),
+		schedulerConfigV1beta2: append([]string{"healthzBindAddress", "metricsBindAddress"}, schedulerConfigCommonFields...),
+		schedulerConfigV1beta3: schedulerConfigCommonFields,
+		schedulerConfigV1:      schedulerConfigCommonFields,
+	}
+	schedulerExtenderFields = []string{
+		"urlPrefix", "filterVerb", "preemptVerb", "prioritizeVerb", "weight",
+		"bindVerb", "enableHTTPS", "tlsConfig", "httpTimeout",
+		"nodeCacheCapable", "managedResources", "ignorable",
+	}
+	schedulerLeaderElectionFields = []string{
+		"leaderElect", "leaseDuration", "renewDeadline", "retryPeriod",
+		"resourceLock", "resourceName", "resourceNamespace",
+	}
+)
+
+func TestGetSchedulerConfigVersion(t *testing.T) {
+	cases := []struct {
+		name        string
+		kubeVersion string
+		wantVersion string
+		wantImage   string
+	}{
+		{
+			name:        "unknown version",
+			wantVersion: schedulerConfigV1beta1,
+		},
+		{
+			name:        "v1.20",
+			kubeVersion: "v1.20.5",
+			wantVersion: schedulerConfigV1beta1,
+			wantImage:   "registry.k8s.io/kube-scheduler:v1.20.5",
+		},
+		{
+			name:        "v1.22",
+			kubeVersion: "v1.22.3",
+			wantVersion: schedulerConfigV1beta2,
+			wantImage:   "registry.k8s.io/kube-scheduler:v1.22.3",
+		},
+		{
+			name:        "v1.23 with distro suffix",
+			kubeVersion: "v1.23.4+k3s1",
+			wantVersion: schedulerConfigV1beta3,
+			wantImage:   "registry.k8s.io/kube-scheduler:v1.23.4",
+		},
+		{
+			name:        "v1.24",
+			kubeVersion: "v1.24.0",
+			wantVersion: schedulerConfigV1beta3,
+			wantImage:   "registry.k8s.io/kube-scheduler:v1.24.0",
+		},
+		{
+			name:        "v1.25",
+			kubeVersion: "v1.25.2-eks-ba74326",
+			wantVersion: schedulerConfigV1,
+			wantImage:   "registry.k8s.io/kube-scheduler:v1.25.2",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var kubeVersion *version.Version
+			if tc.kubeVersion != "" {
+				kubeVersion = version.MustParseGeneric(tc.kubeVersion)
+			}
+			assert.Equal(t, tc.wantVersion, getSchedulerConfigVersion(kubeVersion))
+			assert.Equal(t, schedulerPackage+"-"+tc.wantVersion, getSchedulerPackage(kubeVersion))
+			assert.Equal(t, tc.wantImage, getDefaultKubeSchedulerImage(defaultKubeSchedulerImage, kubeVersion))
+		})
+	}
+}
+
+func TestSchedulerConfigSchema(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+	}
+
+	cases := []struct {
+		kubeVersion string
+		wantVersion string
+	}{
+		{kubeVersion: "v1.21.0", wantVersion: schedulerConfigV1beta1},
+		{kubeVersion: "v1.22.0", wantVersion: schedulerConfigV1beta2},
+		{kubeVersion: "v1.23.0", wantVersion: schedulerConfigV1beta3},
+		{kubeVersion: "v1.25.0", wantVersion: schedulerConfigV1},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.wantVersion, func(t *testing.T) {
+			b, err := getSchedulerBuilder(fs, cluster, nil, version.MustParseGeneric(tc.kubeVersion), "")
+			assert.Nil(t, err)
+
+			config, deployment := getRenderedScheduler(t, b.Manifest())
+
+			assert.Equal(t, "kubescheduler.config.k8s.io/"+tc.wantVersion, config["apiVersion"])
+			assert.Equal(t, "KubeSchedulerConfiguration", config["kind"])
+			assertFields(t, config, schedulerConfigFields[tc.wantVersion])
+
+			leaderElection, ok := config["leaderElection"].(map[string]interface{})
+			assert.True(t, ok)
+			assertFields(t, leaderElection, schedulerLeaderElectionFields)
+			assert.Equal(t, "some-ns", leaderElection["resourceNamespace"])
+
+			extenders, ok := config["extenders"].([]interface{})
+			assert.True(t, ok)
+			assert.Len(t, extenders, 1)
+			extender, ok := extenders[0].(map[string]interface{})
+			assert.True(t, ok)
+			assertFields(t, extender, schedulerExtenderFields)
+			assert.Equal(t, "http://storageos.some-ns.svc:5705/v2/k8s/scheduler", extender["urlPrefix"])
+			assert.Equal(t, false, extender["enableHTTPS"])
+
+			// The kube-scheduler version must match the kubernetes version.
+			assert.Equal(t, "registry.k8s.io/kube-scheduler:"+tc.kubeVersion, deployment.Spec.Template.Spec.Containers[0].Image)
+		})
+	}
+}
+
+func TestSchedulerProfile(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	percentage := int32(50)
+	weight := int32(3)
+
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+		Spec: storageoscomv1.StorageOSClusterSpec{
+			Scheduler: storageoscomv1.StorageOSClusterScheduler{
+				Mode:                     storageoscomv1.SchedulerModeProfile,
+				PercentageOfNodesToScore: &percentage,
+				Plugins: []storageoscomv1.SchedulerPluginSet{
+					{
+						ExtensionPoint: "score",
+						Enabled:        []storageoscomv1.SchedulerPlugin{{Name: "NodeResourcesBalancedAllocation", Weight: &weight}},
+						Disabled:       []string{"ImageLocality"},
+					},
+				},
+			},
+		},
+	}
+
+	b, err := getSchedulerBuilder(fs, cluster, nil, version.MustParseGeneric("v1.25.0"), "")
+	assert.Nil(t, err)
+
+	config, _ := getRenderedScheduler(t, b.Manifest())
+	assertFields(t, config, schedulerConfigFields[schedulerConfigV1])
+
+	// The extender is removed in profile mode.
+	assert.NotContains(t, config, "extenders")
+	assert.EqualValues(t, percentage, config["percentageOfNodesToScore"])
+
+	profiles, ok := config["profiles"].([]interface{})
+	assert.True(t, ok)
+	assert.Len(t, profiles, 1)
+	wantProfile := map[string]interface{}{
+		"schedulerName": "storageos-scheduler",
+		"plugins": map[string]interface{}{
+			"score": map[string]interface{}{
+				"enabled": []interface{}{
+					map[string]interface{}{"name": "NodeResourcesBalancedAllocation", "weight": float64(3)},
+				},
+				"disabled": []interface{}{
+					map[string]interface{}{"name": "ImageLocality"},
+				},
+			},
+		},
+	}
+	assert.Equal(t, wantProfile, profiles[0])
+
+	// multiPoint isn't supported before v1beta3.
+	cluster.Spec.Scheduler.Plugins[0].ExtensionPoint = "multiPoint"
+	_, err = getSchedulerBuilder(fs, cluster, nil, version.MustParseGeneric("v1.22.0"), "")
+	assert.NotNil(t, err)
+	_, err = getSchedulerBuilder(fs, cluster, nil, version.MustParseGeneric("v1.23.0"), "")
+	assert.Nil(t, err)
+}
+
+func TestValidateScheduler(t *testing.T) {
+	weight := int32(2)
+
+	cases := []struct {
+		name    string
+		plugins []storageoscomv1.SchedulerPluginSet
+		wantErr bool
+	}{
+		{
+			name: "valid configuration",
+			plugins: []storageoscomv1.SchedulerPluginSet{
+				{
+					ExtensionPoint: "score",
+					Enabled:        []storageoscomv1.SchedulerPlugin{{Name: "ImageLocality", Weight: &weight}},
+				},
+				{
+					ExtensionPoint: "filter",
+					Disabled:       []string{"*"},
+				},
+			},
+		},
+		{
+			name: "duplicate extension point",
+			plugins: []storageoscomv1.SchedulerPluginSet{
+				{ExtensionPoint: "filter", Disabled: []string{"NodePorts"}},
+				{ExtensionPoint: "filter", Disabled: []string{"NodeAffinity"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "weight on filter plugin",
+			plugins: []storageoscomv1.SchedulerPluginSet{
+				{
+					ExtensionPoint: "filter",
+					Enabled:        []storageoscomv1.SchedulerPlugin{{Name: "NodePorts", Weight: &weight}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty plugin name",
+			plugins: []storageoscomv1.SchedulerPluginSet{
+				{ExtensionPoint: "score", Disabled: []string{""}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{}
+			cluster.Spec.Scheduler.Plugins = tc.plugins
+			err := ValidateScheduler(cluster)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+// getRenderedScheduler returns the scheduler configuration and deployment
+// from a rendered scheduler manifest.
+func getRenderedScheduler(t *testing.T, manifest string) (map[string]interface{}, *appsv1.Deployment) {
+	t.Helper()
+
+	var config map[string]interface{}
+	var deployment *appsv1.Deployment
+	for _, doc := range strings.Split(manifest, "\n---\n") {
+		if strings.Contains(doc, "kind: ConfigMap") {
+			cm := &corev1.ConfigMap{}
+			assert.Nil(t, yaml.Unmarshal([]byte(doc), cm))
+			assert.Nil(t, yaml.Unmarshal([]byte(cm.Data["config.yaml"]), &config))
+		}
+		if strings.Contains(doc, "kind: Deployment") {
+			deployment = &appsv1.Deployment{}
+			assert.Nil(t, yaml.Unmarshal([]byte(doc), deployment))
+		}
+	}
+	assert.NotNil(t, config)
+	assert.NotNil(t, deployment)
+
+	return config, deployment
+}
+
+// assertFields checks that all the fields of an object are in the list of
+// known fields.
+func assertFields(t *testing.T, obj map[string]interface{}, knownFields []string) {
+	t.Helper()
+	for field := range obj {
+		assert.Contains(t, knownFields, field)
+	}
+}
+
+func TestGetSchedulerBuilderImageRegistry(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cluster := &storageoscomv1.StorageOSCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+	}
+
+	b, err := getSchedulerBuilder(fs, cluster, nil, version.MustParseGeneric("v1.25.2"), "registry.example.com")
+	assert.Nil(t, err)
+
+	_, deployment := getRenderedScheduler(t, b.Manifest())
+	assert.Equal(t, "registry.example.com/kube-scheduler:v1.25.2", deployment.Spec.Template.Spec.Containers[0].Image)
+}
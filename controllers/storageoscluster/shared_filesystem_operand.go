@@ -0,0 +1,241 @@
+package storageoscluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/darkowlzz/operator-toolkit/declarative"
+	"github.com/darkowlzz/operator-toolkit/declarative/kubectl"
+	"github.com/darkowlzz/operator-toolkit/declarative/transform"
+	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/api/filesys"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	stransform "github.com/storageos/operator/internal/transform"
+)
+
+const (
+	// sharedFilesystemPackage contains the resource manifests for shared
+	// filesystem operand.
+	sharedFilesystemPackage = "shared-filesystem"
+
+	// sharedFilesystemClusterRoleBinding is the name of the shared
+	// filesystem ClusterRoleBinding.
+	sharedFilesystemClusterRoleBinding = "storageos:shared-filesystem"
+)
+
+// storageClassGVK is the GroupVersionKind of StorageClass.
+var storageClassGVK = storagev1.SchemeGroupVersion.WithKind("StorageClass")
+
+// sharedFilesystemStorageClassLabels are the labels of the ReadWriteMany
+// StorageClasses created by the shared filesystem package.
+var sharedFilesystemStorageClassLabels = map[string]string{appLabel: "storageos", componentLabel: sharedFilesystemPackage}
+
+type SharedFilesystemOperand struct {
+	name            string
+	client          client.Client
+	apiReader       client.Reader
+	requires        []string
+	requeueStrategy operand.RequeueStrategy
+	fs              filesys.FileSystem
+	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
+}
+
+var _ operand.Operand = &SharedFilesystemOperand{}
+
+func (sf *SharedFilesystemOperand) Name() string       { return sf.name }
+func (sf *SharedFilesystemOperand) Requires() []string { return sf.requires }
+func (sf *SharedFilesystemOperand) RequeueStrategy() operand.RequeueStrategy {
+	return sf.requeueStrategy
+}
+func (sf *SharedFilesystemOperand) ReadyCheck(ctx context.Context, obj client.Object) (bool, error) {
+	return true, nil
+}
+func (sf *SharedFilesystemOperand) PostReady(ctx context.Context, obj client.Object) error {
+	return nil
+}
+
+func (sf *SharedFilesystemOperand) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+	ctx, span, _, log := instrumentation.Start(ctx, "SharedFilesystemOperand.Ensure")
+	defer span.End()
+
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// Delete the resources applied before shared filesystems were disabled.
+	if !cluster.Spec.SharedFilesystem.Enable {
+		log.V(4).Info("shared filesystems not enabled")
+		event, err := sf.deleteDisabled(ctx, cluster)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return event, err
+	}
+
+	b, err := getSharedFilesystemBuilder(sf.fs, obj, sf.kubectlClient)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// The StorageClass parameters are immutable. Delete the existing
+	// ReadWriteMany StorageClass if it has changed, to be recreated by apply.
+	desired, err := getStorageClassFromManifest(b.Manifest())
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := deleteChangedStorageClass(ctx, sf.client, desired); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// The ClusterRoleBinding is used to tell if the resources were created or
+	// updated.
+	primary := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: sharedFilesystemClusterRoleBinding}}
+	event, err := applyWithEvent(ctx, sf.apiReader, sf.recorder, b, obj, sharedFilesystemPackage, primary)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Delete the ReadWriteMany StorageClass left behind by a rename.
+	deleted, err := deleteStaleObjects(ctx, sf.client, storageClassGVK, sharedFilesystemStorageClassLabels, desired.GetName())
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if len(deleted) > 0 {
+		log.Info("deleted renamed ReadWriteMany StorageClasses", "names", deleted)
+	}
+	return event, nil
+}
+
+// deleteDisabled deletes the shared filesystem resources of a cluster with
+// shared filesystems disabled.
+func (sf *SharedFilesystemOperand) deleteDisabled(ctx context.Context, cluster *storageoscomv1.StorageOSCluster) (eventv1.ReconcilerEvent, error) {
+	b, err := newSharedFilesystemBuilder(sf.fs, cluster, sf.kubectlClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// The shared filesystem ClusterRoleBinding marks the applied resources.
+	marker := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: sharedFilesystemClusterRoleBinding}}
+	event, err := deleteDisabledWithEvent(ctx, sf.apiReader, b, cluster, sharedFilesystemPackage, marker)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ReadWriteMany StorageClass may have been renamed before being
+	// disabled.
+	if _, err := deleteStaleObjects(ctx, sf.client, storageClassGVK, sharedFilesystemStorageClassLabels, ""); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (sf *SharedFilesystemOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
+	ctx, span, _, _ := instrumentation.Start(ctx, "SharedFilesystemOperand.Delete")
+	defer span.End()
+
+	b, err := getSharedFilesystemBuilder(sf.fs, obj, sf.kubectlClient)
+	if err != nil {
+		if errors.Is(err, noResourceErr) {
+			return nil, nil
+		}
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return deleteWithEvent(ctx, b, obj, sharedFilesystemPackage)
+}
+
+func getSharedFilesystemBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// Skip if shared filesystems aren't enabled.
+	if !cluster.Spec.SharedFilesystem.Enable {
+		return nil, noResourceErr
+	}
+
+	return newSharedFilesystemBuilder(fs, cluster, kcl)
+}
+
+// newSharedFilesystemBuilder returns the shared filesystem resource builder,
+// whether shared filesystems are enabled or not.
+func newSharedFilesystemBuilder(fs filesys.FileSystem, cluster *storageoscomv1.StorageOSCluster, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+	// Set the ReadWriteMany StorageClass name and secret reference, like the
+	// main StorageClass.
+	scTransforms := []transform.TransformFunc{
+		stransform.SetMetadataNameFunc(cluster.GetSharedFilesystemStorageClassName()),
+	}
+	scTransforms = append(scTransforms, getStorageClassSecretTransforms(cluster)...)
+
+	// Set the namespace of the node service account. All the resources are
+	// cluster scoped, the namespace can't be added to the package.
+	roleBindingTransforms := []transform.TransformFunc{
+		stransform.SetClusterRoleBindingSubjectNamespaceFunc("storageos-daemonset-sa", cluster.GetNamespace()),
+	}
+
+	// Add the common labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return declarative.NewBuilder(sharedFilesystemPackage, fs,
+		declarative.WithManifestTransform(transform.ManifestTransform{
+			"shared-filesystem/storageclass.yaml":         scTransforms,
+			"shared-filesystem/cluster-role-binding.yaml": roleBindingTransforms,
+		}),
+		declarative.WithKustomizeMutationFunc(labelsMutateFuncs),
+		declarative.WithKubectlClient(kcl),
+	)
+}
+
+// ValidateSharedFilesystem validates the shared filesystem configuration of a
+// cluster. The ReadWriteMany StorageClass can't replace the main StorageClass.
+func ValidateSharedFilesystem(cluster *storageoscomv1.StorageOSCluster) error {
+	if !cluster.Spec.SharedFilesystem.Enable {
+		return nil
+	}
+	if name := cluster.GetSharedFilesystemStorageClassName(); name == cluster.Spec.StorageClassName {
+		return fmt.Errorf("sharedFilesystem: storageClassName %q is the name of the main StorageClass", name)
+	}
+	return nil
+}
+
+func NewSharedFilesystemOperand(
+	name string,
+	client client.Client,
+	apiReader client.Reader,
+	requires []string,
+	requeueStrategy operand.RequeueStrategy,
+	fs filesys.FileSystem,
+	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
+) *SharedFilesystemOperand {
+	return &SharedFilesystemOperand{
+		name:            name,
+		client:          client,
+		apiReader:       apiReader,
+		requires:        requires,
+		requeueStrategy: requeueStrategy,
+		fs:              fs,
+		kubectlClient:   kcl,
+		recorder:        recorder,
+	}
+}
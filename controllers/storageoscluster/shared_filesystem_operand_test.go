@@ -0,0 +1,190 @@
+package storageoscluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestGetSharedFilesystemBuilder(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name                 string
+		sharedFilesystem     storageoscomv1.StorageOSClusterSharedFilesystem
+		wantNoResource       bool
+		wantStorageClassName string
+	}{
+		{
+			name:           "disabled",
+			wantNoResource: true,
+		},
+		{
+			name:                 "enabled",
+			sharedFilesystem:     storageoscomv1.StorageOSClusterSharedFilesystem{Enable: true},
+			wantStorageClassName: "storageos-rwx",
+		},
+		{
+			name: "custom StorageClass name",
+			sharedFilesystem: storageoscomv1.StorageOSClusterSharedFilesystem{
+				Enable:           true,
+				StorageClassName: "foo-rwx",
+			},
+			wantStorageClassName: "foo-rwx",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+			}
+			cluster.Spec.SecretRefName = "some-secret"
+			cluster.Spec.SharedFilesystem = tc.sharedFilesystem
+
+			b, err := getSharedFilesystemBuilder(fs, cluster, nil)
+			if tc.wantNoResource {
+				assert.ErrorIs(t, err, noResourceErr)
+				return
+			}
+			assert.Nil(t, err)
+
+			sc, err := getStorageClassFromManifest(b.Manifest())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantStorageClassName, sc.Name)
+			assert.Equal(t, map[string]string{
+				csiSecretNameKey:            "some-secret",
+				csiSecretNamespaceKey:       "some-ns",
+				"csi.storage.k8s.io/fstype": "ext4",
+			}, sc.Parameters)
+
+			var crb *rbacv1.ClusterRoleBinding
+			for _, doc := range strings.Split(b.Manifest(), "\n---\n") {
+				if strings.Contains(doc, "kind: ClusterRoleBinding") {
+					crb = &rbacv1.ClusterRoleBinding{}
+					assert.Nil(t, yaml.Unmarshal([]byte(doc), crb))
+				}
+			}
+			assert.NotNil(t, crb)
+
+			for _, subject := range crb.Subjects {
+				assert.Equal(t, "some-ns", subject.Namespace)
+			}
+		})
+	}
+}
+
+func TestSharedFilesystemOperandEnsure(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	marker := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: sharedFilesystemClusterRoleBinding}}
+	reclaimDelete := corev1.PersistentVolumeReclaimDelete
+	immediate := storagev1.VolumeBindingImmediate
+	storageClass := func(name string, parameters map[string]string) *storagev1.StorageClass {
+		return &storagev1.StorageClass{
+			ObjectMeta:        metav1.ObjectMeta{Name: name, Labels: sharedFilesystemStorageClassLabels},
+			Provisioner:       CSIDriverName,
+			Parameters:        parameters,
+			ReclaimPolicy:     &reclaimDelete,
+			VolumeBindingMode: &immediate,
+		}
+	}
+	parameters := map[string]string{
+		csiSecretNameKey:            "some-secret",
+		csiSecretNamespaceKey:       "some-ns",
+		"csi.storage.k8s.io/fstype": "ext4",
+	}
+
+	cases := []struct {
+		name        string
+		enable      bool
+		existing    []client.Object
+		wantApply   bool
+		wantDelete  bool
+		wantDeleted []string
+		wantKept    []string
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:        "disabled after being applied",
+			existing:    []client.Object{marker, storageClass("storageos-rwx", parameters)},
+			wantDelete:  true,
+			wantDeleted: []string{"storageos-rwx"},
+		},
+		{
+			name:      "enabled",
+			enable:    true,
+			wantApply: true,
+		},
+		{
+			name:      "unchanged StorageClass",
+			enable:    true,
+			existing:  []client.Object{storageClass("storageos-rwx", parameters)},
+			wantApply: true,
+			wantKept:  []string{"storageos-rwx"},
+		},
+		{
+			name:        "changed StorageClass",
+			enable:      true,
+			existing:    []client.Object{storageClass("storageos-rwx", map[string]string{csiSecretNameKey: "old-secret"})},
+			wantApply:   true,
+			wantDeleted: []string{"storageos-rwx"},
+		},
+		{
+			name:        "renamed StorageClass",
+			enable:      true,
+			existing:    []client.Object{storageClass("old-rwx", parameters)},
+			wantApply:   true,
+			wantDeleted: []string{"old-rwx"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithObjects(tc.existing...).Build()
+			kcl := &fakeKubectl{}
+			op := NewSharedFilesystemOperand(sharedFSOpName, cl, cl, []string{}, operand.RequeueOnError, fs, kcl, record.NewFakeRecorder(10))
+
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+			}
+			cluster.Spec.SecretRefName = "some-secret"
+			cluster.Spec.SharedFilesystem.Enable = tc.enable
+
+			_, err := op.Ensure(context.TODO(), cluster, metav1.OwnerReference{})
+			assert.Nil(t, err)
+
+			assert.Equal(t, tc.wantApply, len(kcl.applied) > 0)
+			assert.Equal(t, tc.wantDelete, len(kcl.deleted) > 0)
+
+			for _, name := range tc.wantDeleted {
+				err := cl.Get(context.TODO(), client.ObjectKey{Name: name}, &storagev1.StorageClass{})
+				assert.True(t, apierrors.IsNotFound(err), "StorageClass %q not deleted", name)
+			}
+			for _, name := range tc.wantKept {
+				assert.Nil(t, cl.Get(context.TODO(), client.ObjectKey{Name: name}, &storagev1.StorageClass{}))
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package storageoscluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/darkowlzz/operator-toolkit/declarative"
+	"github.com/darkowlzz/operator-toolkit/declarative/kubectl"
+	"github.com/darkowlzz/operator-toolkit/declarative/transform"
+	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
+	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/api/filesys"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	stransform "github.com/storageos/operator/internal/transform"
+)
+
+const (
+	// snapshotPackage contains the resource manifests for snapshot operand.
+	snapshotPackage = "snapshot"
+
+	// volumeSnapshotGroup is the API group of the volume snapshot CRDs.
+	volumeSnapshotGroup = "snapshot.storage.k8s.io"
+
+	// snapshotterClusterRoleBinding is the name of the snapshotter
+	// ClusterRoleBinding.
+	snapshotterClusterRoleBinding = "storageos:csi-snapshotter"
+
+	// VolumeSnapshotClass snapshotter secret parameter keys.
+	snapshotterSecretNameKey      = "csi.storage.k8s.io/snapshotter-secret-name"
+	snapshotterSecretNamespaceKey = "csi.storage.k8s.io/snapshotter-secret-namespace"
+)
+
+// volumeSnapshotKinds are the volume snapshot kinds required by the CSI
+// external snapshotter.
+var volumeSnapshotKinds = []string{"VolumeSnapshot", "VolumeSnapshotClass", "VolumeSnapshotContent"}
+
+// volumeSnapshotClassGVK is the GroupVersionKind of the VolumeSnapshotClass
+// in the snapshot package.
+var volumeSnapshotClassGVK = schema.GroupVersionKind{Group: volumeSnapshotGroup, Version: "v1", Kind: "VolumeSnapshotClass"}
+
+// volumeSnapshotClassLabels are the labels of the VolumeSnapshotClasses
+// created by the snapshot package.
+var volumeSnapshotClassLabels = map[string]string{appLabel: "storageos", componentLabel: csiComponent}
+
+type SnapshotOperand struct {
+	name            string
+	client          client.Client
+	apiReader       client.Reader
+	requires        []string
+	requeueStrategy operand.RequeueStrategy
+	fs              filesys.FileSystem
+	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
+}
+
+var _ operand.Operand = &SnapshotOperand{}
+
+func (s *SnapshotOperand) Name() string                             { return s.name }
+func (s *SnapshotOperand) Requires() []string                       { return s.requires }
+func (s *SnapshotOperand) RequeueStrategy() operand.RequeueStrategy { return s.requeueStrategy }
+func (s *SnapshotOperand) ReadyCheck(ctx context.Context, obj client.Object) (bool, error) {
+	return true, nil
+}
+func (s *SnapshotOperand) PostReady(ctx context.Context, obj client.Object) error { return nil }
+
+func (s *SnapshotOperand) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+	ctx, span, _, log := instrumentation.Start(ctx, "SnapshotOperand.Ensure")
+	defer span.End()
+
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// The volume snapshot CRDs are installed separately. The cluster status
+	// reports the missing CRDs.
+	served, err := isGroupKindsServed(s.client, volumeSnapshotGroup, volumeSnapshotKinds)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Delete the resources applied before volume snapshots were disabled.
+	if !cluster.Spec.Snapshot.Enable {
+		log.V(4).Info("volume snapshots not enabled")
+		if !served {
+			return nil, nil
+		}
+		event, err := s.deleteDisabled(ctx, cluster)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return event, err
+	}
+
+	if !served {
+		log.Info("volume snapshot CRDs not found, skipping VolumeSnapshotClass creation")
+		return nil, nil
+	}
+
+	b, err := getSnapshotBuilder(s.fs, obj, s.kubectlClient)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	event, err := applyWithEvent(ctx, s.apiReader, s.recorder, b, obj, snapshotPackage, nil)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Delete the VolumeSnapshotClass left behind by a rename.
+	deleted, err := deleteStaleObjects(ctx, s.client, volumeSnapshotClassGVK, volumeSnapshotClassLabels, cluster.GetVolumeSnapshotClassName())
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if len(deleted) > 0 {
+		log.Info("deleted renamed VolumeSnapshotClasses", "names", deleted)
+	}
+	return event, nil
+}
+
+// deleteDisabled deletes the volume snapshot resources of a cluster with
+// volume snapshots disabled.
+func (s *SnapshotOperand) deleteDisabled(ctx context.Context, cluster *storageoscomv1.StorageOSCluster) (eventv1.ReconcilerEvent, error) {
+	b, err := newSnapshotBuilder(s.fs, cluster, s.kubectlClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// The snapshotter ClusterRoleBinding marks the applied resources.
+	marker := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: snapshotterClusterRoleBinding}}
+	event, err := deleteDisabledWithEvent(ctx, s.apiReader, b, cluster, snapshotPackage, marker)
+	if err != nil {
+		return nil, err
+	}
+
+	// The VolumeSnapshotClass may have been renamed before being disabled.
+	if _, err := deleteStaleObjects(ctx, s.client, volumeSnapshotClassGVK, volumeSnapshotClassLabels, ""); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (s *SnapshotOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
+	ctx, span, _, _ := instrumentation.Start(ctx, "SnapshotOperand.Delete")
+	defer span.End()
+
+	b, err := getSnapshotBuilder(s.fs, obj, s.kubectlClient)
+	if err != nil {
+		if errors.Is(err, noResourceErr) {
+			return nil, nil
+		}
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Nothing to delete if the volume snapshot CRDs aren't served.
+	served, err := isGroupKindsServed(s.client, volumeSnapshotGroup, volumeSnapshotKinds)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if !served {
+		return nil, nil
+	}
+
+	return deleteWithEvent(ctx, b, obj, snapshotPackage)
+}
+
+func getSnapshotBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+
+	// Skip if volume snapshots aren't enabled.
+	if !cluster.Spec.Snapshot.Enable {
+		return nil, noResourceErr
+	}
+
+	return newSnapshotBuilder(fs, cluster, kcl)
+}
+
+// newSnapshotBuilder returns the volume snapshot resource builder, whether
+// volume snapshots are enabled or not.
+func newSnapshotBuilder(fs filesys.FileSystem, cluster *storageoscomv1.StorageOSCluster, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
+	// VolumeSnapshotClass transforms.
+	vscTransforms := []transform.TransformFunc{
+		stransform.SetMetadataNameFunc(cluster.GetVolumeSnapshotClassName()),
+		stransform.SetScalarNodeStringValueFunc("deletionPolicy", string(cluster.GetVolumeSnapshotDeletionPolicy())),
+		stransform.SetScalarNodeStringValueFunc(snapshotterSecretNameKey, cluster.Spec.SecretRefName, "parameters"),
+		stransform.SetScalarNodeStringValueFunc(snapshotterSecretNamespaceKey, cluster.Namespace, "parameters"),
+	}
+
+	// Set the namespace of the CSI helper service account. All the resources
+	// are cluster scoped, the namespace can't be added to the package.
+	roleBindingTransforms := []transform.TransformFunc{
+		stransform.SetClusterRoleBindingSubjectNamespaceFunc("storageos-csi-helper-sa", cluster.GetNamespace()),
+	}
+
+	// Add the common labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return declarative.NewBuilder(snapshotPackage, fs,
+		declarative.WithManifestTransform(transform.ManifestTransform{
+			"snapshot/volumesnapshotclass.yaml":              vscTransforms,
+			"snapshot/snapshotter-cluster-role-binding.yaml": roleBindingTransforms,
+		}),
+		declarative.WithKustomizeMutationFunc(labelsMutateFuncs),
+		declarative.WithKubectlClient(kcl),
+	)
+}
+
+func NewSnapshotOperand(
+	name string,
+	client client.Client,
+	apiReader client.Reader,
+	requires []string,
+	requeueStrategy operand.RequeueStrategy,
+	fs filesys.FileSystem,
+	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
+) *SnapshotOperand {
+	return &SnapshotOperand{
+		name:            name,
+		client:          client,
+		apiReader:       apiReader,
+		requires:        requires,
+		requeueStrategy: requeueStrategy,
+		fs:              fs,
+		kubectlClient:   kcl,
+		recorder:        recorder,
+	}
+}
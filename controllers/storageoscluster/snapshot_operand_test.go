@@ -0,0 +1,89 @@
+package storageoscluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestGetSnapshotBuilder(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	// volumeSnapshotClass is a partial VolumeSnapshotClass, to avoid
+	// importing the external snapshotter API.
+	type volumeSnapshotClass struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Driver            string            `json:"driver"`
+		DeletionPolicy    string            `json:"deletionPolicy"`
+		Parameters        map[string]string `json:"parameters"`
+	}
+
+	cases := []struct {
+		name           string
+		snapshot       storageoscomv1.StorageOSClusterSnapshot
+		wantNoResource bool
+		wantName       string
+		wantPolicy     string
+	}{
+		{
+			name:           "disabled",
+			wantNoResource: true,
+		},
+		{
+			name:       "defaults",
+			snapshot:   storageoscomv1.StorageOSClusterSnapshot{Enable: true},
+			wantName:   "storageos-snapshotclass",
+			wantPolicy: "Delete",
+		},
+		{
+			name: "custom class",
+			snapshot: storageoscomv1.StorageOSClusterSnapshot{
+				Enable:                  true,
+				VolumeSnapshotClassName: "foo",
+				DeletionPolicy:          storageoscomv1.VolumeSnapshotDeletionPolicyRetain,
+			},
+			wantName:   "foo",
+			wantPolicy: "Retain",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+			}
+			cluster.Spec.SecretRefName = "some-secret"
+			cluster.Spec.Snapshot = tc.snapshot
+
+			b, err := getSnapshotBuilder(fs, cluster, nil)
+			if tc.wantNoResource {
+				assert.ErrorIs(t, err, noResourceErr)
+				return
+			}
+			assert.Nil(t, err)
+
+			var vsc *volumeSnapshotClass
+			for _, doc := range strings.Split(b.Manifest(), "\n---\n") {
+				if strings.Contains(doc, "kind: VolumeSnapshotClass") {
+					vsc = &volumeSnapshotClass{}
+					assert.Nil(t, yaml.Unmarshal([]byte(doc), vsc))
+				}
+			}
+			assert.NotNil(t, vsc)
+
+			assert.Equal(t, tc.wantName, vsc.Name)
+			assert.Equal(t, CSIDriverName, vsc.Driver)
+			assert.Equal(t, tc.wantPolicy, vsc.DeletionPolicy)
+			assert.Equal(t, "some-secret", vsc.Parameters[snapshotterSecretNameKey])
+			assert.Equal(t, "some-ns", vsc.Parameters[snapshotterSecretNamespaceKey])
+		})
+	}
+}
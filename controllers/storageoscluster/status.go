@@ -0,0 +1,155 @@
+package storageoscluster
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+// Top-level cluster condition types.
+const (
+	readyType       = "Ready"
+	progressingType = "Progressing"
+	degradedType    = "Degraded"
+)
+
+// componentConditionTypes are the condition types of the components that
+// must be ready for the cluster to be ready, in the order they're reported.
+var componentConditionTypes = []string{
+	schedulerReadyType,
+	nodeReadyType,
+	apiManagerReadyType,
+	csiReadyType,
+}
+
+// getClusterPhase returns the phase of the cluster based on the component
+// conditions. The previous phase is used to tell apart a new cluster that's
+// being created from a running cluster that's being upgraded or that broke.
+func getClusterPhase(deleting bool, prevPhase string, conditions []metav1.Condition) string {
+	if deleting {
+		return storageoscomv1.ClusterPhaseTerminating
+	}
+
+	ready, degraded, progressing := 0, false, false
+	for _, condType := range componentConditionTypes {
+		c := meta.FindStatusCondition(conditions, condType)
+		switch {
+		case c == nil:
+		case c.Status == metav1.ConditionTrue:
+			ready++
+		case c.Reason == degradedReason:
+			degraded = true
+		case c.Reason == progressingReason:
+			progressing = true
+		}
+	}
+	notReady := len(componentConditionTypes) - ready
+
+	switch {
+	case notReady == 0:
+		return storageoscomv1.ClusterPhaseRunning
+	case degraded:
+		return storageoscomv1.ClusterPhaseDegraded
+	}
+
+	// A cluster that has been running doesn't go back to creating.
+	switch prevPhase {
+	case storageoscomv1.ClusterPhaseRunning, storageoscomv1.ClusterPhaseUpgrading:
+		// Only component rollouts are an upgrade. Missing or failing
+		// components degrade the cluster.
+		if progressing && notReady == countConditionsWithReason(conditions, progressingReason) {
+			return storageoscomv1.ClusterPhaseUpgrading
+		}
+		return storageoscomv1.ClusterPhaseDegraded
+	case storageoscomv1.ClusterPhaseDegraded:
+		return storageoscomv1.ClusterPhaseDegraded
+	}
+
+	if ready > 0 {
+		return storageoscomv1.ClusterPhaseCreating
+	}
+	return storageoscomv1.ClusterPhasePending
+}
+
+// countConditionsWithReason returns the number of component conditions that
+// aren't true with the given reason.
+func countConditionsWithReason(conditions []metav1.Condition, reason string) int {
+	count := 0
+	for _, condType := range componentConditionTypes {
+		c := meta.FindStatusCondition(conditions, condType)
+		if c != nil && c.Status != metav1.ConditionTrue && c.Reason == reason {
+			count++
+		}
+	}
+	return count
+}
+
+// setClusterConditions sets the top-level Ready, Progressing and Degraded
+// conditions of the cluster based on the cluster phase. The condition
+// messages list the components that aren't ready.
+func setClusterConditions(conditions *[]metav1.Condition, phase string) {
+	message := getNotReadyMessage(*conditions)
+
+	readyCondition := metav1.Condition{
+		Type:    readyType,
+		Status:  metav1.ConditionFalse,
+		Reason:  phase,
+		Message: message,
+	}
+	progressingCondition := metav1.Condition{
+		Type:    progressingType,
+		Status:  metav1.ConditionFalse,
+		Reason:  phase,
+		Message: message,
+	}
+	degradedCondition := metav1.Condition{
+		Type:    degradedType,
+		Status:  metav1.ConditionFalse,
+		Reason:  phase,
+		Message: message,
+	}
+
+	switch phase {
+	case storageoscomv1.ClusterPhaseRunning:
+		readyCondition.Status = metav1.ConditionTrue
+		readyCondition.Reason = readyReason
+		readyCondition.Message = "Cluster Ready"
+		progressingCondition.Message = "Cluster Ready"
+		degradedCondition.Message = "Cluster Ready"
+	case storageoscomv1.ClusterPhaseDegraded:
+		degradedCondition.Status = metav1.ConditionTrue
+	case storageoscomv1.ClusterPhaseTerminating:
+		progressingCondition.Status = metav1.ConditionTrue
+		readyCondition.Message = "Cluster Terminating"
+		progressingCondition.Message = "Cluster Terminating"
+		degradedCondition.Message = "Cluster Terminating"
+	case storageoscomv1.ClusterPhasePending,
+		storageoscomv1.ClusterPhaseCreating,
+		storageoscomv1.ClusterPhaseUpgrading:
+		progressingCondition.Status = metav1.ConditionTrue
+	}
+
+	meta.SetStatusCondition(conditions, readyCondition)
+	meta.SetStatusCondition(conditions, progressingCondition)
+	meta.SetStatusCondition(conditions, degradedCondition)
+}
+
+// getNotReadyMessage returns the messages of the component conditions that
+// aren't true, in the component order.
+func getNotReadyMessage(conditions []metav1.Condition) string {
+	messages := []string{}
+	for _, condType := range componentConditionTypes {
+		c := meta.FindStatusCondition(conditions, condType)
+		if c == nil {
+			messages = append(messages, condType+" unknown")
+			continue
+		}
+		if c.Status != metav1.ConditionTrue {
+			messages = append(messages, c.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
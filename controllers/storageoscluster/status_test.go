@@ -0,0 +1,209 @@
+package storageoscluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+// componentConditions returns the component conditions with the given
+// reasons, in the order of componentConditionTypes.
+func componentConditions(reasons ...string) []metav1.Condition {
+	conditions := []metav1.Condition{}
+	for i, reason := range reasons {
+		status := metav1.ConditionFalse
+		if reason == availableReason {
+			status = metav1.ConditionTrue
+		}
+		conditions = append(conditions, metav1.Condition{
+			Type:    componentConditionTypes[i],
+			Status:  status,
+			Reason:  reason,
+			Message: componentConditionTypes[i] + " " + reason,
+		})
+	}
+	return conditions
+}
+
+func TestGetClusterPhase(t *testing.T) {
+	cases := []struct {
+		name       string
+		deleting   bool
+		prevPhase  string
+		conditions []metav1.Condition
+		want       string
+	}{
+		{
+			name: "no conditions",
+			want: storageoscomv1.ClusterPhasePending,
+		},
+		{
+			name:       "no ready components",
+			conditions: componentConditions(notFoundReason, progressingReason, notFoundReason, notFoundReason),
+			want:       storageoscomv1.ClusterPhasePending,
+		},
+		{
+			name:       "some ready components",
+			conditions: componentConditions(availableReason, progressingReason, notFoundReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseCreating,
+		},
+		{
+			name:       "all ready components",
+			prevPhase:  storageoscomv1.ClusterPhaseCreating,
+			conditions: componentConditions(availableReason, availableReason, availableReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseRunning,
+		},
+		{
+			name:       "partially ready component",
+			prevPhase:  storageoscomv1.ClusterPhaseCreating,
+			conditions: componentConditions(availableReason, degradedReason, availableReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseDegraded,
+		},
+		{
+			name:       "running cluster with rollout",
+			prevPhase:  storageoscomv1.ClusterPhaseRunning,
+			conditions: componentConditions(availableReason, progressingReason, availableReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseUpgrading,
+		},
+		{
+			name:       "upgrading cluster with rollout",
+			prevPhase:  storageoscomv1.ClusterPhaseUpgrading,
+			conditions: componentConditions(progressingReason, availableReason, progressingReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseUpgrading,
+		},
+		{
+			name:       "upgraded cluster",
+			prevPhase:  storageoscomv1.ClusterPhaseUpgrading,
+			conditions: componentConditions(availableReason, availableReason, availableReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseRunning,
+		},
+		{
+			name:       "running cluster with missing component",
+			prevPhase:  storageoscomv1.ClusterPhaseRunning,
+			conditions: componentConditions(availableReason, availableReason, notFoundReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseDegraded,
+		},
+		{
+			name:       "running cluster with rollout and missing component",
+			prevPhase:  storageoscomv1.ClusterPhaseRunning,
+			conditions: componentConditions(availableReason, progressingReason, notFoundReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseDegraded,
+		},
+		{
+			name:       "degraded cluster with rollout",
+			prevPhase:  storageoscomv1.ClusterPhaseDegraded,
+			conditions: componentConditions(availableReason, progressingReason, availableReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseDegraded,
+		},
+		{
+			name:       "recovered cluster",
+			prevPhase:  storageoscomv1.ClusterPhaseDegraded,
+			conditions: componentConditions(availableReason, availableReason, availableReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseRunning,
+		},
+		{
+			name:       "deleting cluster",
+			deleting:   true,
+			prevPhase:  storageoscomv1.ClusterPhaseRunning,
+			conditions: componentConditions(availableReason, availableReason, availableReason, availableReason),
+			want:       storageoscomv1.ClusterPhaseTerminating,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, getClusterPhase(tc.deleting, tc.prevPhase, tc.conditions))
+		})
+	}
+}
+
+func TestSetClusterConditions(t *testing.T) {
+	cases := []struct {
+		name            string
+		phase           string
+		conditions      []metav1.Condition
+		wantReady       metav1.ConditionStatus
+		wantProgressing metav1.ConditionStatus
+		wantDegraded    metav1.ConditionStatus
+		wantMessage     string
+	}{
+		{
+			name:            "pending",
+			phase:           storageoscomv1.ClusterPhasePending,
+			wantReady:       metav1.ConditionFalse,
+			wantProgressing: metav1.ConditionTrue,
+			wantDegraded:    metav1.ConditionFalse,
+			wantMessage:     "SchedulerReady unknown; NodeReady unknown; APIManagerReady unknown; CSIReady unknown",
+		},
+		{
+			name:            "creating",
+			phase:           storageoscomv1.ClusterPhaseCreating,
+			conditions:      componentConditions(availableReason, progressingReason, notFoundReason, availableReason),
+			wantReady:       metav1.ConditionFalse,
+			wantProgressing: metav1.ConditionTrue,
+			wantDegraded:    metav1.ConditionFalse,
+			wantMessage:     "NodeReady Progressing; APIManagerReady NotFound",
+		},
+		{
+			name:            "running",
+			phase:           storageoscomv1.ClusterPhaseRunning,
+			conditions:      componentConditions(availableReason, availableReason, availableReason, availableReason),
+			wantReady:       metav1.ConditionTrue,
+			wantProgressing: metav1.ConditionFalse,
+			wantDegraded:    metav1.ConditionFalse,
+			wantMessage:     "Cluster Ready",
+		},
+		{
+			name:            "degraded",
+			phase:           storageoscomv1.ClusterPhaseDegraded,
+			conditions:      componentConditions(availableReason, degradedReason, availableReason, availableReason),
+			wantReady:       metav1.ConditionFalse,
+			wantProgressing: metav1.ConditionFalse,
+			wantDegraded:    metav1.ConditionTrue,
+			wantMessage:     "NodeReady Degraded",
+		},
+		{
+			name:            "upgrading",
+			phase:           storageoscomv1.ClusterPhaseUpgrading,
+			conditions:      componentConditions(availableReason, progressingReason, availableReason, availableReason),
+			wantReady:       metav1.ConditionFalse,
+			wantProgressing: metav1.ConditionTrue,
+			wantDegraded:    metav1.ConditionFalse,
+			wantMessage:     "NodeReady Progressing",
+		},
+		{
+			name:            "terminating",
+			phase:           storageoscomv1.ClusterPhaseTerminating,
+			conditions:      componentConditions(availableReason, availableReason, availableReason, availableReason),
+			wantReady:       metav1.ConditionFalse,
+			wantProgressing: metav1.ConditionTrue,
+			wantDegraded:    metav1.ConditionFalse,
+			wantMessage:     "Cluster Terminating",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			conditions := tc.conditions
+
+			// Start from a ready cluster to ensure the conditions can go
+			// false again.
+			meta.SetStatusCondition(&conditions, metav1.Condition{Type: readyType, Status: metav1.ConditionTrue, Reason: readyReason})
+
+			setClusterConditions(&conditions, tc.phase)
+
+			ready := meta.FindStatusCondition(conditions, readyType)
+			assert.NotNil(t, ready)
+			assert.Equal(t, tc.wantReady, ready.Status)
+			assert.Equal(t, tc.wantMessage, ready.Message)
+			assert.Equal(t, tc.wantProgressing, meta.FindStatusCondition(conditions, progressingType).Status)
+			assert.Equal(t, tc.wantDegraded, meta.FindStatusCondition(conditions, degradedType).Status)
+		})
+	}
+}
@@ -4,15 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/darkowlzz/operator-toolkit/declarative"
 	"github.com/darkowlzz/operator-toolkit/declarative/kubectl"
 	"github.com/darkowlzz/operator-toolkit/declarative/transform"
 	eventv1 "github.com/darkowlzz/operator-toolkit/event/v1"
 	"github.com/darkowlzz/operator-toolkit/operator/v1/operand"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kustomize/api/filesys"
+	"sigs.k8s.io/yaml"
 
 	storageoscomv1 "github.com/storageos/operator/apis/v1"
 	stransform "github.com/storageos/operator/internal/transform"
@@ -30,6 +36,13 @@ const (
 	// namespace.
 	csiSecretNamespaceKey = "csi.storage.k8s.io/secret-namespace"
 
+	// StorageClass parameter key prefixes for the CSI secret of each
+	// operation. These are suffixed with "-name" and "-namespace".
+	csiProvisionerSecretPrefix       = "csi.storage.k8s.io/provisioner-secret"
+	csiControllerPublishSecretPrefix = "csi.storage.k8s.io/controller-publish-secret"
+	csiNodePublishSecretPrefix       = "csi.storage.k8s.io/node-publish-secret"
+	csiControllerExpandSecretPrefix  = "csi.storage.k8s.io/controller-expand-secret"
+
 	// storageClassParameterPath is the path to StorageClass parameters.
 	storageClassParametersPath = "parameters"
 )
@@ -37,10 +50,12 @@ const (
 type StorageClassOperand struct {
 	name            string
 	client          client.Client
+	apiReader       client.Reader
 	requires        []string
 	requeueStrategy operand.RequeueStrategy
 	fs              filesys.FileSystem
 	kubectlClient   kubectl.KubectlClient
+	recorder        record.EventRecorder
 }
 
 var _ operand.Operand = &StorageClassOperand{}
@@ -54,7 +69,7 @@ func (sc *StorageClassOperand) ReadyCheck(ctx context.Context, obj client.Object
 func (c *StorageClassOperand) PostReady(ctx context.Context, obj client.Object) error { return nil }
 
 func (sc *StorageClassOperand) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
-	_, span, _, log := instrumentation.Start(ctx, "StorageClassOperand.Ensure")
+	ctx, span, _, log := instrumentation.Start(ctx, "StorageClassOperand.Ensure")
 	defer span.End()
 
 	b, err := getStorageClassBuilder(sc.fs, obj, sc.kubectlClient)
@@ -67,7 +82,21 @@ func (sc *StorageClassOperand) Ensure(ctx context.Context, obj client.Object, ow
 		return nil, err
 	}
 
-	return nil, b.Apply(ctx)
+	// The StorageClass parameters are immutable. Delete the existing
+	// StorageClass if it has changed, to be recreated by apply.
+	desired, err := getStorageClassFromManifest(b.Manifest())
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := deleteChangedStorageClass(ctx, sc.client, desired); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// The StorageClass is used to tell if the resources were created or updated.
+	primary := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: desired.GetName()}}
+	return applyWithEvent(ctx, sc.apiReader, sc.recorder, b, obj, storageclassPackage, primary)
 }
 
 func (sc *StorageClassOperand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
@@ -83,7 +112,7 @@ func (sc *StorageClassOperand) Delete(ctx context.Context, obj client.Object) (e
 		return nil, err
 	}
 
-	return nil, b.Delete(ctx)
+	return deleteWithEvent(ctx, b, obj, storageclassPackage)
 }
 
 func getStorageClassBuilder(fs filesys.FileSystem, obj client.Object, kcl kubectl.KubectlClient) (*declarative.Builder, error) {
@@ -103,34 +132,147 @@ func getStorageClassBuilder(fs filesys.FileSystem, obj client.Object, kcl kubect
 	// Set the StorageClass name.
 	nameTF := stransform.SetMetadataNameFunc(cluster.Spec.StorageClassName)
 
+	scTransforms = append(scTransforms, nameTF)
+
 	// Set secret reference.
-	secretNameTF := stransform.SetScalarNodeStringValueFunc(csiSecretNameKey, cluster.Spec.SecretRefName, storageClassParametersPath)
-	secretNamespaceTF := stransform.SetScalarNodeStringValueFunc(csiSecretNamespaceKey, cluster.Namespace, storageClassParametersPath)
+	scTransforms = append(scTransforms, getStorageClassSecretTransforms(cluster)...)
 
-	scTransforms = append(scTransforms, nameTF, secretNameTF, secretNamespaceTF)
+	// Add the common labels and annotations.
+	labelsMutateFuncs, err := getLabelsMutateFuncs(cluster)
+	if err != nil {
+		return nil, err
+	}
 
 	return declarative.NewBuilder(storageclassPackage, fs,
 		declarative.WithManifestTransform(transform.ManifestTransform{
 			"storageclass/storageclass.yaml": scTransforms,
 		}),
+		declarative.WithKustomizeMutationFunc(labelsMutateFuncs),
 		declarative.WithKubectlClient(kcl),
 	)
 }
 
+// getStorageClassSecretTransforms returns the transforms to set the CSI
+// secret reference parameters of a StorageClass. The secret is set for all
+// the CSI operations, unless the credentials of specific operations are
+// enabled.
+func getStorageClassSecretTransforms(cluster *storageoscomv1.StorageOSCluster) []transform.TransformFunc {
+	secretPrefixes := getCSISecretPrefixes(cluster)
+	if len(secretPrefixes) == 0 {
+		return []transform.TransformFunc{
+			stransform.SetScalarNodeStringValueFunc(csiSecretNameKey, cluster.Spec.SecretRefName, storageClassParametersPath),
+			stransform.SetScalarNodeStringValueFunc(csiSecretNamespaceKey, cluster.Namespace, storageClassParametersPath),
+		}
+	}
+
+	transforms := []transform.TransformFunc{
+		stransform.RemoveFieldFunc(csiSecretNameKey, storageClassParametersPath),
+		stransform.RemoveFieldFunc(csiSecretNamespaceKey, storageClassParametersPath),
+	}
+	for _, prefix := range secretPrefixes {
+		transforms = append(transforms,
+			stransform.SetScalarNodeStringValueFunc(prefix+"-name", cluster.Spec.SecretRefName, storageClassParametersPath),
+			stransform.SetScalarNodeStringValueFunc(prefix+"-namespace", cluster.Namespace, storageClassParametersPath),
+		)
+	}
+	return transforms
+}
+
+// getCSISecretPrefixes returns the StorageClass parameter key prefixes of the
+// CSI operations with credentials enabled.
+func getCSISecretPrefixes(cluster *storageoscomv1.StorageOSCluster) []string {
+	prefixes := []string{}
+	if cluster.Spec.CSI.EnableProvisionCreds {
+		prefixes = append(prefixes, csiProvisionerSecretPrefix)
+	}
+	if cluster.Spec.CSI.EnableControllerPublishCreds {
+		prefixes = append(prefixes, csiControllerPublishSecretPrefix)
+	}
+	if cluster.Spec.CSI.EnableNodePublishCreds {
+		prefixes = append(prefixes, csiNodePublishSecretPrefix)
+	}
+	if cluster.Spec.CSI.EnableControllerExpandCreds {
+		prefixes = append(prefixes, csiControllerExpandSecretPrefix)
+	}
+	return prefixes
+}
+
+// getStorageClassFromManifest returns the StorageClass in the given manifest.
+func getStorageClassFromManifest(manifest string) (*storagev1.StorageClass, error) {
+	for _, doc := range strings.Split(manifest, "\n---\n") {
+		obj := &storagev1.StorageClass{}
+		if err := yaml.Unmarshal([]byte(doc), obj); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal manifest: %w", err)
+		}
+		if obj.Kind == "StorageClass" {
+			return obj, nil
+		}
+	}
+	return nil, errors.New("StorageClass not found in manifest")
+}
+
+// storageClassChanged checks if the immutable fields of the current
+// StorageClass differ from the desired StorageClass. Fields that aren't set in
+// the desired StorageClass are defaulted by the API server and ignored.
+func storageClassChanged(current, desired *storagev1.StorageClass) bool {
+	if current.Provisioner != desired.Provisioner {
+		return true
+	}
+	if !reflect.DeepEqual(current.Parameters, desired.Parameters) {
+		return true
+	}
+	if desired.ReclaimPolicy != nil && (current.ReclaimPolicy == nil || *current.ReclaimPolicy != *desired.ReclaimPolicy) {
+		return true
+	}
+	if desired.VolumeBindingMode != nil && (current.VolumeBindingMode == nil || *current.VolumeBindingMode != *desired.VolumeBindingMode) {
+		return true
+	}
+	return false
+}
+
+// deleteChangedStorageClass deletes the existing StorageClass if it differs
+// from the desired StorageClass. Existing volumes aren't affected, the
+// StorageClass is only used when provisioning new volumes.
+func deleteChangedStorageClass(ctx context.Context, cl client.Client, desired *storagev1.StorageClass) error {
+	ctx, span, _, log := instrumentation.Start(ctx, "deleteChangedStorageClass")
+	defer span.End()
+
+	current := &storagev1.StorageClass{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: desired.GetName()}, current); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get StorageClass %q: %w", desired.GetName(), err)
+	}
+	if !storageClassChanged(current, desired) {
+		return nil
+	}
+
+	log.Info("StorageClass changed, recreating", "name", desired.GetName())
+	if err := cl.Delete(ctx, current); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete StorageClass %q: %w", desired.GetName(), err)
+	}
+	return nil
+}
+
 func NewStorageClassOperand(
 	name string,
 	client client.Client,
+	apiReader client.Reader,
 	requires []string,
 	requeueStrategy operand.RequeueStrategy,
 	fs filesys.FileSystem,
 	kcl kubectl.KubectlClient,
+	recorder record.EventRecorder,
 ) *StorageClassOperand {
 	return &StorageClassOperand{
 		name:            name,
 		client:          client,
+		apiReader:       apiReader,
 		requires:        requires,
 		requeueStrategy: requeueStrategy,
 		fs:              fs,
 		kubectlClient:   kcl,
+		recorder:        recorder,
 	}
 }
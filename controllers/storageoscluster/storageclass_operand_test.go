@@ -0,0 +1,142 @@
+package storageoscluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/darkowlzz/operator-toolkit/declarative/loader"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestGetStorageClassBuilder(t *testing.T) {
+	fs, err := loader.NewLoadedManifestFileSystem("../../channels", "stable")
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name           string
+		csi            storageoscomv1.StorageOSClusterCSI
+		wantParameters map[string]string
+	}{
+		{
+			name: "defaults",
+			wantParameters: map[string]string{
+				csiSecretNameKey:            "some-secret",
+				csiSecretNamespaceKey:       "some-ns",
+				"csi.storage.k8s.io/fstype": "ext4",
+			},
+		},
+		{
+			name: "provision and expand creds",
+			csi: storageoscomv1.StorageOSClusterCSI{
+				EnableProvisionCreds:        true,
+				EnableControllerExpandCreds: true,
+			},
+			wantParameters: map[string]string{
+				csiProvisionerSecretPrefix + "-name":           "some-secret",
+				csiProvisionerSecretPrefix + "-namespace":      "some-ns",
+				csiControllerExpandSecretPrefix + "-name":      "some-secret",
+				csiControllerExpandSecretPrefix + "-namespace": "some-ns",
+				"csi.storage.k8s.io/fstype":                    "ext4",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "some-ns"},
+			}
+			cluster.Spec.StorageClassName = "foo-class"
+			cluster.Spec.SecretRefName = "some-secret"
+			cluster.Spec.CSI = tc.csi
+
+			b, err := getStorageClassBuilder(fs, cluster, nil)
+			assert.Nil(t, err)
+
+			sc, err := getStorageClassFromManifest(b.Manifest())
+			assert.Nil(t, err)
+			assert.Equal(t, "foo-class", sc.Name)
+			assert.Equal(t, tc.wantParameters, sc.Parameters)
+		})
+	}
+}
+
+func TestDeleteChangedStorageClass(t *testing.T) {
+	retain := corev1.PersistentVolumeReclaimRetain
+	immediate := storagev1.VolumeBindingImmediate
+
+	desired := &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "fast"},
+		Provisioner:       CSIDriverName,
+		Parameters:        map[string]string{csiSecretNameKey: "storageos-api"},
+		VolumeBindingMode: &immediate,
+	}
+
+	cases := []struct {
+		name        string
+		current     *storagev1.StorageClass
+		wantDeleted bool
+	}{
+		{
+			name: "not found",
+		},
+		{
+			name:    "unchanged",
+			current: desired.DeepCopy(),
+		},
+		{
+			name: "labels changed",
+			current: func() *storagev1.StorageClass {
+				sc := desired.DeepCopy()
+				sc.SetLabels(map[string]string{"foo": "bar"})
+				return sc
+			}(),
+		},
+		{
+			name: "parameters changed",
+			current: func() *storagev1.StorageClass {
+				sc := desired.DeepCopy()
+				sc.Parameters = map[string]string{csiProvisionerSecretPrefix + "-name": "storageos-api"}
+				return sc
+			}(),
+			wantDeleted: true,
+		},
+		{
+			name: "defaulted reclaim policy",
+			current: func() *storagev1.StorageClass {
+				sc := desired.DeepCopy()
+				sc.ReclaimPolicy = &retain
+				return sc
+			}(),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder()
+			if tc.current != nil {
+				builder = builder.WithObjects(tc.current)
+			}
+			cl := builder.Build()
+
+			err := deleteChangedStorageClass(context.TODO(), cl, desired)
+			assert.Nil(t, err)
+
+			if tc.current == nil {
+				return
+			}
+			err = cl.Get(context.TODO(), client.ObjectKey{Name: desired.Name}, &storagev1.StorageClass{})
+			assert.Equal(t, tc.wantDeleted, apierrors.IsNotFound(err))
+		})
+	}
+}
@@ -3,23 +3,39 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	compositev1 "github.com/darkowlzz/operator-toolkit/controller/composite/v1"
 	"github.com/darkowlzz/operator-toolkit/declarative/loader"
 	"github.com/darkowlzz/operator-toolkit/operator/v1/executor"
 	tkpredicate "github.com/darkowlzz/operator-toolkit/predicate"
 	"github.com/darkowlzz/operator-toolkit/telemetry"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	configstorageoscomv1 "github.com/storageos/operator/apis/config.storageos.com/v1"
 	storageoscomv1 "github.com/storageos/operator/apis/v1"
 	"github.com/storageos/operator/controllers/storageoscluster"
+	"github.com/storageos/operator/internal/health"
 )
 
 const instrumentationName = "github.com/storageos/operator/controllers"
 
+// nodeConfigRefIndex is the field index of the clusters by node configmap
+// name.
+const nodeConfigRefIndex = "spec.nodeConfigRefName"
+
 var instrumentation *telemetry.Instrumentation
 
 func init() {
@@ -35,14 +51,63 @@ type StorageOSClusterReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 
+	// KubeVersion is the version of the kubernetes API server. It's used to
+	// render version specific resources.
+	KubeVersion *version.Version
+
+	// K8sDistro is the detected kubernetes distribution. It's used to apply
+	// the distribution defaults when a cluster doesn't specify one.
+	K8sDistro string
+
+	// ReconcileTracker tracks the in-flight reconciles for the liveness
+	// check. Optional.
+	ReconcileTracker *health.ReconcileTracker
+
+	// Config is the operator configuration.
+	Config configstorageoscomv1.OperatorConfig
+
 	compositev1.CompositeReconciler
 }
 
-func NewStorageOSClusterReconciler(mgr ctrl.Manager) *StorageOSClusterReconciler {
+func NewStorageOSClusterReconciler(mgr ctrl.Manager, kubeVersion *version.Version, k8sDistro string, tracker *health.ReconcileTracker, config configstorageoscomv1.OperatorConfig) *StorageOSClusterReconciler {
 	return &StorageOSClusterReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		KubeVersion:      kubeVersion,
+		K8sDistro:        k8sDistro,
+		ReconcileTracker: tracker,
+		Config:           config,
+	}
+}
+
+// Reconcile reconciles a StorageOSCluster with the composite reconciler,
+// tracking the reconcile duration for the liveness check. Successfully
+// reconciled clusters are requeued after the resync interval, if enabled.
+func (r *StorageOSClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.ReconcileTracker != nil {
+		done := r.ReconcileTracker.Begin()
+		defer done()
+	}
+
+	result, err := r.CompositeReconciler.Reconcile(ctx, req)
+	if err != nil || result.Requeue || result.RequeueAfter > 0 {
+		return result, err
+	}
+	return r.resync(ctx, req, result)
+}
+
+// resync requeues the cluster after the resync interval. Deleted clusters
+// aren't requeued.
+func (r *StorageOSClusterReconciler) resync(ctx context.Context, req ctrl.Request, result ctrl.Result) (ctrl.Result, error) {
+	interval := r.Config.GetResyncInterval()
+	if interval == 0 {
+		return result, nil
+	}
+	if err := r.Get(ctx, req.NamespacedName, &storageoscomv1.StorageOSCluster{}); err != nil {
+		return result, client.IgnoreNotFound(err)
 	}
+	result.RequeueAfter = interval
+	return result, nil
 }
 
 // +kubebuilder:rbac:groups=storageos.com,resources=storageosclusters,verbs=get;list;watch;create;update;patch;delete
@@ -57,14 +122,13 @@ func (r *StorageOSClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	_, span, _, log := instrumentation.Start(context.Background(), "StorageosCluster.SetupWithManager")
 	defer span.End()
 
-	// Load manifests in an in-memory filesystem.
-	fs, err := loader.NewLoadedManifestFileSystem("channels", "stable")
+	// Load manifests of the configured channel in an in-memory filesystem.
+	fs, err := loader.NewLoadedManifestFileSystem("channels", r.Config.GetChannel())
 	if err != nil {
 		return fmt.Errorf("failed to create loaded ManifestFileSystem: %w", err)
 	}
 
-	// TODO: Expose the executor strategy option via SetupWithManager.
-	cc, err := storageoscluster.NewStorageOSClusterController(mgr, fs, executor.Parallel)
+	cc, err := storageoscluster.NewStorageOSClusterController(mgr, fs, getExecutionStrategy(r.Config.GetExecutionStrategy()), r.KubeVersion, r.K8sDistro, r.Config)
 	if err != nil {
 		return err
 	}
@@ -80,15 +144,125 @@ func (r *StorageOSClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return fmt.Errorf("failed to create new CompositeReconciler: %w", err)
 	}
 
+	// Index the clusters by node configmap to find the clusters referring
+	// to a changed configmap.
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &storageoscomv1.StorageOSCluster{}, nodeConfigRefIndex, indexNodeConfigRef); err != nil {
+		return fmt.Errorf("failed to index node config references: %w", err)
+	}
+
 	// Use the GenerationChangedPredicate to ignore the status update events
 	// but capture the events due to labels, annotations and finalizers change.
+	// Also watch the metadata of the user provided node configmaps to apply
+	// any changes to the node configuration. Only the configmaps referred to
+	// by a cluster are enqueued. Failed reconciles are retried with an
+	// exponential backoff, up to the configured maximum delay. The events
+	// outside of the watched namespaces are ignored. The cache isn't scoped to
+	// the watched namespaces, the cluster scoped objects of the operands are
+	// read through it. Instead, the cluster webhook rejects the clusters
+	// created outside the watched namespaces.
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&storageoscomv1.StorageOSCluster{}).
-		WithEventFilter(predicate.Or(
+		WithOptions(controller.Options{
+			RateLimiter: newRateLimiter(r.Config.GetMaxBackoff()),
+		}).
+		WithEventFilter(inNamespacesPredicate(r.Config.WatchNamespaces)).
+		For(&storageoscomv1.StorageOSCluster{}, builder.WithPredicates(predicate.Or(
 			predicate.GenerationChangedPredicate{},
 			predicate.LabelChangedPredicate{},
 			predicate.AnnotationChangedPredicate{},
 			tkpredicate.FinalizerChangedPredicate{},
-		)).
+		))).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.nodeConfigToCluster),
+			builder.OnlyMetadata,
+			builder.WithPredicates(
+				predicate.ResourceVersionChangedPredicate{},
+				predicate.NewPredicateFuncs(r.isNodeConfig),
+			),
+		).
 		Complete(r)
 }
+
+// getExecutionStrategy returns the operator executor strategy of the given
+// configured execution strategy.
+func getExecutionStrategy(strategy configstorageoscomv1.ExecutionStrategy) executor.ExecutionStrategy {
+	if strategy == configstorageoscomv1.ExecutionStrategySerial {
+		return executor.Serial
+	}
+	return executor.Parallel
+}
+
+// inNamespacesPredicate returns a predicate that filters the objects in the
+// given namespaces. All the objects pass when no namespace is given.
+func inNamespacesPredicate(namespaces []string) predicate.Predicate {
+	watched := map[string]bool{}
+	for _, ns := range namespaces {
+		watched[ns] = true
+	}
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return len(watched) == 0 || watched[obj.GetNamespace()]
+	})
+}
+
+// newRateLimiter returns the controller-runtime default rate limiter, with
+// the given maximum delay of the per item exponential backoff.
+func newRateLimiter(maxDelay time.Duration) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, maxDelay),
+		// Overall rate limit of 10 qps, with a burst of 100.
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
+// indexNodeConfigRef returns the node configmap name of a cluster for the
+// node config reference index.
+func indexNodeConfigRef(obj client.Object) []string {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok || cluster.Spec.NodeConfigRefName == "" {
+		return nil
+	}
+	return []string{cluster.Spec.NodeConfigRefName}
+}
+
+// getNodeConfigClusters returns the StorageOSClusters in the same namespace
+// as the given configmap that refer to it as the node configuration.
+func (r *StorageOSClusterReconciler) getNodeConfigClusters(ctx context.Context, obj client.Object) (*storageoscomv1.StorageOSClusterList, error) {
+	clusters := &storageoscomv1.StorageOSClusterList{}
+	err := r.List(ctx, clusters,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{nodeConfigRefIndex: obj.GetName()},
+	)
+	return clusters, err
+}
+
+// isNodeConfig returns true if the given configmap is the node
+// configuration of a cluster.
+func (r *StorageOSClusterReconciler) isNodeConfig(obj client.Object) bool {
+	clusters, err := r.getNodeConfigClusters(context.Background(), obj)
+	if err != nil {
+		// Let the mapping report the error.
+		return true
+	}
+	return len(clusters.Items) > 0
+}
+
+// nodeConfigToCluster maps a configmap to the StorageOSClusters in the same
+// namespace that refer to it as the node configuration.
+func (r *StorageOSClusterReconciler) nodeConfigToCluster(obj client.Object) []reconcile.Request {
+	ctx, span, _, log := instrumentation.Start(context.Background(), "StorageosCluster.nodeConfigToCluster")
+	defer span.End()
+
+	clusters, err := r.getNodeConfigClusters(ctx, obj)
+	if err != nil {
+		log.Error(err, "failed to list StorageOSClusters")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cluster := range clusters.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&cluster),
+		})
+	}
+	return requests
+}
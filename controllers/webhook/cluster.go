@@ -3,6 +3,7 @@ package webhook
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/darkowlzz/operator-toolkit/singleton"
 	"github.com/darkowlzz/operator-toolkit/telemetry"
@@ -10,12 +11,15 @@ import (
 	"github.com/darkowlzz/operator-toolkit/webhook/builder"
 	"github.com/darkowlzz/operator-toolkit/webhook/function"
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/controllers/storageoscluster"
 )
 
 const (
@@ -44,13 +48,18 @@ type StorageOSClusterWebhook struct {
 	Log      logr.Logger
 	Client   client.Client
 
+	// WatchNamespaces are the namespaces of the reconciled clusters. All
+	// the namespaces are watched when empty.
+	WatchNamespaces []string
+
 	singletonGetInstance singleton.GetInstanceFunc
 }
 
 var _ tkadmission.Controller = &StorageOSClusterWebhook{}
 
 // NewStorageOSClusterWebhook constructs a webhook controller and returns it.
-func NewStorageOSClusterWebhook(cl client.Client, scheme *runtime.Scheme) (*StorageOSClusterWebhook, error) {
+// The clusters created outside the watched namespaces are rejected.
+func NewStorageOSClusterWebhook(cl client.Client, scheme *runtime.Scheme, watchNamespaces []string) (*StorageOSClusterWebhook, error) {
 	_, _, _, log := instrumentation.Start(context.Background(), "NewStorageOSClusterWebhook")
 
 	// Instantiate the singleton function for the target object and set it in
@@ -64,6 +73,7 @@ func NewStorageOSClusterWebhook(cl client.Client, scheme *runtime.Scheme) (*Stor
 		Scheme:               scheme,
 		Log:                  log,
 		Client:               cl,
+		WatchNamespaces:      watchNamespaces,
 		singletonGetInstance: getInstance,
 	}, nil
 }
@@ -106,14 +116,28 @@ func (wh *StorageOSClusterWebhook) Default() []tkadmission.DefaultFunc {
 // returns a list of validate on create functions.
 func (wh *StorageOSClusterWebhook) ValidateCreate() []tkadmission.ValidateCreateFunc {
 	return []tkadmission.ValidateCreateFunc{
+		wh.validateNamespaceCreate,
 		function.ValidateSingletonCreate(wh.singletonGetInstance, wh.Client),
+		validateNodeContainersCreate,
+		wh.validateNodeConfigCreate,
+		validateLabelsCreate,
+		validateSchedulerCreate,
+		validateCSICreate,
+		validateSharedFilesystemCreate,
 	}
 }
 
 // ValidateUpdate implements the admission webhook controller interface. It
 // returns a list of validate on update functions.
 func (wh *StorageOSClusterWebhook) ValidateUpdate() []tkadmission.ValidateUpdateFunc {
-	return []tkadmission.ValidateUpdateFunc{}
+	return []tkadmission.ValidateUpdateFunc{
+		validateNodeContainersUpdate,
+		wh.validateNodeConfigUpdate,
+		validateLabelsUpdate,
+		validateSchedulerUpdate,
+		validateCSIUpdate,
+		validateSharedFilesystemUpdate,
+	}
 }
 
 // ValidateDelete implements the admission webhook controller interface. It
@@ -122,6 +146,130 @@ func (wh *StorageOSClusterWebhook) ValidateDelete() []tkadmission.ValidateDelete
 	return []tkadmission.ValidateDeleteFunc{}
 }
 
+// validateNamespaceCreate rejects a new StorageOSCluster outside the watched
+// namespaces. The operator wouldn't reconcile it. The namespace of a cluster
+// can't change, updates aren't validated.
+func (wh *StorageOSClusterWebhook) validateNamespaceCreate(ctx context.Context, obj client.Object) error {
+	if len(wh.WatchNamespaces) == 0 {
+		return nil
+	}
+	for _, ns := range wh.WatchNamespaces {
+		if obj.GetNamespace() == ns {
+			return nil
+		}
+	}
+	return fmt.Errorf("StorageOSCluster namespace %q isn't watched by the operator, watched namespaces: %s", obj.GetNamespace(), strings.Join(wh.WatchNamespaces, ", "))
+}
+
+// validateNodeContainersCreate validates the node containers configurations
+// of a new StorageOSCluster.
+func validateNodeContainersCreate(ctx context.Context, obj client.Object) error {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+	return storageoscluster.ValidateNodeContainers(cluster)
+}
+
+// validateNodeContainersUpdate validates the node containers configurations
+// of an updated StorageOSCluster.
+func validateNodeContainersUpdate(ctx context.Context, obj client.Object, oldObj client.Object) error {
+	return validateNodeContainersCreate(ctx, obj)
+}
+
+// validateLabelsCreate validates the common and pod labels of a new
+// StorageOSCluster.
+func validateLabelsCreate(ctx context.Context, obj client.Object) error {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+	return storageoscluster.ValidateLabels(cluster)
+}
+
+// validateLabelsUpdate validates the common and pod labels of an updated
+// StorageOSCluster.
+func validateLabelsUpdate(ctx context.Context, obj client.Object, oldObj client.Object) error {
+	return validateLabelsCreate(ctx, obj)
+}
+
+// validateSchedulerCreate validates the scheduler configuration of a new
+// StorageOSCluster.
+func validateSchedulerCreate(ctx context.Context, obj client.Object) error {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+	return storageoscluster.ValidateScheduler(cluster)
+}
+
+// validateSchedulerUpdate validates the scheduler configuration of an updated
+// StorageOSCluster.
+func validateSchedulerUpdate(ctx context.Context, obj client.Object, oldObj client.Object) error {
+	return validateSchedulerCreate(ctx, obj)
+}
+
+// validateCSICreate validates the CSI configuration of a new
+// StorageOSCluster.
+func validateCSICreate(ctx context.Context, obj client.Object) error {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+	return storageoscluster.ValidateCSI(cluster)
+}
+
+// validateCSIUpdate validates the CSI configuration of an updated
+// StorageOSCluster.
+func validateCSIUpdate(ctx context.Context, obj client.Object, oldObj client.Object) error {
+	return validateCSICreate(ctx, obj)
+}
+
+// validateSharedFilesystemCreate validates the shared filesystem
+// configuration of a new StorageOSCluster.
+func validateSharedFilesystemCreate(ctx context.Context, obj client.Object) error {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+	return storageoscluster.ValidateSharedFilesystem(cluster)
+}
+
+// validateSharedFilesystemUpdate validates the shared filesystem
+// configuration of an updated StorageOSCluster.
+func validateSharedFilesystemUpdate(ctx context.Context, obj client.Object, oldObj client.Object) error {
+	return validateSharedFilesystemCreate(ctx, obj)
+}
+
+// validateNodeConfigCreate validates the node configmap referenced by a new
+// StorageOSCluster. A missing configmap is allowed, it may be created after the
+// cluster.
+func (wh *StorageOSClusterWebhook) validateNodeConfigCreate(ctx context.Context, obj client.Object) error {
+	cluster, ok := obj.(*storageoscomv1.StorageOSCluster)
+	if !ok {
+		return fmt.Errorf("failed to convert %v to StorageOSCluster", obj)
+	}
+	if cluster.Spec.NodeConfigRefName == "" {
+		return nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Name: cluster.Spec.NodeConfigRefName, Namespace: cluster.GetNamespace()}
+	if err := wh.Client.Get(ctx, key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get node config %q: %w", cluster.Spec.NodeConfigRefName, err)
+	}
+	return storageoscluster.ValidateNodeConfig(cm.Data)
+}
+
+// validateNodeConfigUpdate validates the node configmap referenced by an
+// updated StorageOSCluster.
+func (wh *StorageOSClusterWebhook) validateNodeConfigUpdate(ctx context.Context, obj client.Object, oldObj client.Object) error {
+	return wh.validateNodeConfigCreate(ctx, obj)
+}
+
 // SetupWithManager builds the webhook controller, registering the webhook
 // endpoints with the webhook server in the controller manager.
 func (wh *StorageOSClusterWebhook) SetupWithManager(mgr manager.Manager) error {
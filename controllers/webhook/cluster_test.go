@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestValidateNamespaceCreate(t *testing.T) {
+	cases := []struct {
+		name            string
+		watchNamespaces []string
+		wantErr         bool
+	}{
+		{
+			name: "all namespaces watched",
+		},
+		{
+			name:            "namespace watched",
+			watchNamespaces: []string{"foo", "storageos"},
+		},
+		{
+			name:            "namespace not watched",
+			watchNamespaces: []string{"foo"},
+			wantErr:         true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			wh := &StorageOSClusterWebhook{WatchNamespaces: tc.watchNamespaces}
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "storageos", Namespace: "storageos"},
+			}
+
+			err := wh.validateNamespaceCreate(context.TODO(), cluster)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestValidateSharedFilesystemCreate(t *testing.T) {
+	cases := []struct {
+		name             string
+		sharedFilesystem storageoscomv1.StorageOSClusterSharedFilesystem
+		wantErr          bool
+	}{
+		{
+			name: "disabled",
+			sharedFilesystem: storageoscomv1.StorageOSClusterSharedFilesystem{
+				StorageClassName: "storageos",
+			},
+		},
+		{
+			name:             "default StorageClass name",
+			sharedFilesystem: storageoscomv1.StorageOSClusterSharedFilesystem{Enable: true},
+		},
+		{
+			name: "main StorageClass name",
+			sharedFilesystem: storageoscomv1.StorageOSClusterSharedFilesystem{
+				Enable:           true,
+				StorageClassName: "storageos",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &storageoscomv1.StorageOSCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "storageos", Namespace: "storageos"},
+			}
+			cluster.Spec.StorageClassName = "storageos"
+			cluster.Spec.SharedFilesystem = tc.sharedFilesystem
+
+			err := validateSharedFilesystemCreate(context.TODO(), cluster)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
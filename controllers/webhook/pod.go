@@ -0,0 +1,278 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	tkadmission "github.com/darkowlzz/operator-toolkit/webhook/admission"
+	"github.com/darkowlzz/operator-toolkit/webhook/builder"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/controllers/storageoscluster"
+)
+
+const (
+	// podSchedulerWebhookName is the name of the webhook controller that sets
+	// the scheduler of pods with StorageOS volumes.
+	podSchedulerWebhookName = "pod-scheduler-webhook"
+
+	// SchedulerOptOutLabel is the label used to opt a pod or a namespace out
+	// of the storageos scheduler, when set to "false".
+	SchedulerOptOutLabel = "storageos.com/scheduler"
+
+	// legacyProvisioner is the in-tree StorageOS volume plugin provisioner.
+	legacyProvisioner = "kubernetes.io/storageos"
+
+	// betaStorageClassAnnotation is the deprecated PVC annotation for the
+	// StorageClass name.
+	betaStorageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+
+	// defaultStorageClassAnnotation marks the default StorageClass of the
+	// cluster.
+	defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+	// betaDefaultStorageClassAnnotation is the deprecated annotation that
+	// marks the default StorageClass of the cluster.
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+// PodSchedulerWebhook is the webhook controller for Pods. It sets the
+// scheduler of pods that use StorageOS volumes to the storageos scheduler.
+// The webhook fails open, any error results in the pod being admitted
+// unchanged.
+type PodSchedulerWebhook struct {
+	CtrlName string
+	Log      logr.Logger
+	Client   client.Client
+
+	// APIReader reads the PVCs and StorageClasses directly from the API
+	// server. The operator doesn't watch these, reading them through the
+	// cached client would start an informer for every PVC in the cluster.
+	APIReader client.Reader
+}
+
+var _ tkadmission.Controller = &PodSchedulerWebhook{}
+
+// NewPodSchedulerWebhook constructs a pod scheduler webhook controller and
+// returns it.
+func NewPodSchedulerWebhook(cl client.Client, apiReader client.Reader) *PodSchedulerWebhook {
+	_, _, _, log := instrumentation.Start(context.Background(), "NewPodSchedulerWebhook")
+
+	return &PodSchedulerWebhook{
+		CtrlName:  podSchedulerWebhookName,
+		Log:       log,
+		Client:    cl,
+		APIReader: apiReader,
+	}
+}
+
+// Name implements the admission webhook controller interface. It returns the
+// webhook controller's name.
+func (wh *PodSchedulerWebhook) Name() string {
+	return wh.CtrlName
+}
+
+// GetNewObject implements the admission webhook controller interface. It
+// returns an instance of the target object.
+func (wh *PodSchedulerWebhook) GetNewObject() client.Object {
+	return &corev1.Pod{}
+}
+
+// RequireDefaulting implements the admission webhook controller interface. It
+// is used to toggle the defaulter webhook. Pods that opted out, use a custom
+// scheduler or have no persistent volume claims are ignored.
+func (wh *PodSchedulerWebhook) RequireDefaulting(obj client.Object) bool {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return false
+	}
+	if pod.GetLabels()[SchedulerOptOutLabel] == "false" {
+		return false
+	}
+	if pod.Spec.SchedulerName != "" && pod.Spec.SchedulerName != corev1.DefaultSchedulerName {
+		return false
+	}
+	for _, vol := range pod.Spec.Volumes {
+		if vol.PersistentVolumeClaim != nil || vol.Ephemeral != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireValidating implements the admission webhook controller interface. It
+// is used to toggle the validator webhook.
+func (wh *PodSchedulerWebhook) RequireValidating(obj client.Object) bool {
+	return false
+}
+
+// Default implements the admission webhook controller interface. It returns a
+// list of defaulter functions.
+func (wh *PodSchedulerWebhook) Default() []tkadmission.DefaultFunc {
+	return []tkadmission.DefaultFunc{
+		wh.setSchedulerName,
+	}
+}
+
+// ValidateCreate implements the admission webhook controller interface. It
+// returns a list of validate on create functions.
+func (wh *PodSchedulerWebhook) ValidateCreate() []tkadmission.ValidateCreateFunc {
+	return []tkadmission.ValidateCreateFunc{}
+}
+
+// ValidateUpdate implements the admission webhook controller interface. It
+// returns a list of validate on update functions.
+func (wh *PodSchedulerWebhook) ValidateUpdate() []tkadmission.ValidateUpdateFunc {
+	return []tkadmission.ValidateUpdateFunc{}
+}
+
+// ValidateDelete implements the admission webhook controller interface. It
+// returns a list of validate on delete functions.
+func (wh *PodSchedulerWebhook) ValidateDelete() []tkadmission.ValidateDeleteFunc {
+	return []tkadmission.ValidateDeleteFunc{}
+}
+
+// setSchedulerName sets the storageos scheduler as the scheduler of a pod that
+// uses StorageOS volumes. The pod is left unchanged on any error.
+func (wh *PodSchedulerWebhook) setSchedulerName(ctx context.Context, obj client.Object) {
+	ctx, span, _, log := instrumentation.Start(ctx, "PodSchedulerWebhook.setSchedulerName")
+	defer span.End()
+
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	log = log.WithValues("namespace", pod.GetNamespace(), "name", pod.GetName(), "generateName", pod.GetGenerateName())
+
+	// Only route the pods when the storageos scheduler is deployed.
+	clusters := &storageoscomv1.StorageOSClusterList{}
+	if err := wh.Client.List(ctx, clusters); err != nil {
+		span.RecordError(err)
+		log.Error(err, "failed to list StorageOSClusters, skipping pod")
+		return
+	}
+	if len(clusters.Items) == 0 || clusters.Items[0].Spec.DisableScheduler {
+		return
+	}
+
+	found, err := wh.hasStorageOSVolume(ctx, pod)
+	if err != nil {
+		span.RecordError(err)
+		log.Error(err, "failed to check pod volumes, skipping pod")
+		return
+	}
+	if !found {
+		return
+	}
+
+	log.V(4).Info("setting pod scheduler", "schedulerName", storageoscluster.SchedulerName)
+	pod.Spec.SchedulerName = storageoscluster.SchedulerName
+}
+
+// hasStorageOSVolume checks if any of the volume claims of a pod refer to a
+// StorageOS StorageClass. Claims without a StorageClass use the default
+// StorageClass. Claims that don't exist yet are ignored.
+func (wh *PodSchedulerWebhook) hasStorageOSVolume(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	for _, vol := range pod.Spec.Volumes {
+		var scName *string
+		switch {
+		case vol.PersistentVolumeClaim != nil:
+			pvc := &corev1.PersistentVolumeClaim{}
+			key := client.ObjectKey{Name: vol.PersistentVolumeClaim.ClaimName, Namespace: pod.GetNamespace()}
+			if err := wh.APIReader.Get(ctx, key, pvc); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return false, fmt.Errorf("failed to get PVC %q: %w", key.Name, err)
+			}
+			scName = getPVCStorageClassName(pvc)
+		case vol.Ephemeral != nil && vol.Ephemeral.VolumeClaimTemplate != nil:
+			scName = vol.Ephemeral.VolumeClaimTemplate.Spec.StorageClassName
+		default:
+			continue
+		}
+
+		sc, err := wh.getStorageClass(ctx, scName)
+		if err != nil {
+			return false, err
+		}
+		if sc == nil {
+			continue
+		}
+		if sc.Provisioner == storageoscluster.CSIDriverName || sc.Provisioner == legacyProvisioner {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getStorageClass returns the StorageClass of the given name, or the default
+// StorageClass if no name is set. An empty name explicitly requests no
+// StorageClass. nil is returned when no StorageClass is found.
+func (wh *PodSchedulerWebhook) getStorageClass(ctx context.Context, name *string) (*storagev1.StorageClass, error) {
+	if name == nil {
+		return wh.getDefaultStorageClass(ctx)
+	}
+	if *name == "" {
+		return nil, nil
+	}
+
+	sc := &storagev1.StorageClass{}
+	if err := wh.APIReader.Get(ctx, client.ObjectKey{Name: *name}, sc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get StorageClass %q: %w", *name, err)
+	}
+	return sc, nil
+}
+
+// getDefaultStorageClass returns the default StorageClass of the cluster, or
+// nil if there's no default StorageClass.
+func (wh *PodSchedulerWebhook) getDefaultStorageClass(ctx context.Context) (*storagev1.StorageClass, error) {
+	scs := &storagev1.StorageClassList{}
+	if err := wh.APIReader.List(ctx, scs); err != nil {
+		return nil, fmt.Errorf("failed to list StorageClasses: %w", err)
+	}
+	for i, sc := range scs.Items {
+		if isDefaultStorageClass(&sc) {
+			return &scs.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// isDefaultStorageClass returns true if the given StorageClass is marked as
+// the default StorageClass.
+func isDefaultStorageClass(sc *storagev1.StorageClass) bool {
+	annotations := sc.GetAnnotations()
+	return annotations[defaultStorageClassAnnotation] == "true" ||
+		annotations[betaDefaultStorageClassAnnotation] == "true"
+}
+
+// getPVCStorageClassName returns the StorageClass name of a PVC, falling back
+// to the deprecated beta annotation. nil is returned if the PVC doesn't set a
+// StorageClass.
+func getPVCStorageClassName(pvc *corev1.PersistentVolumeClaim) *string {
+	if pvc.Spec.StorageClassName != nil {
+		return pvc.Spec.StorageClassName
+	}
+	if name, ok := pvc.GetAnnotations()[betaStorageClassAnnotation]; ok {
+		return &name
+	}
+	return nil
+}
+
+// SetupWithManager builds the webhook controller, registering the webhook
+// endpoints with the webhook server in the controller manager.
+func (wh *PodSchedulerWebhook) SetupWithManager(mgr manager.Manager) error {
+	return builder.WebhookManagedBy(mgr).
+		MutatePath("/mutate-pods").
+		Complete(wh)
+}
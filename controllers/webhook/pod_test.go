@@ -0,0 +1,225 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/controllers/storageoscluster"
+)
+
+func TestPodSchedulerWebhook(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(storageoscomv1.AddToScheme(scheme))
+
+	storageosSC := "storageos"
+	otherSC := "other"
+	noSC := ""
+
+	objects := []client.Object{
+		&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: storageosSC},
+			Provisioner: storageoscluster.CSIDriverName,
+		},
+		&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: otherSC},
+			Provisioner: "example.com/other",
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "storageos-pvc", Namespace: "default"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &storageosSC},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "beta-pvc",
+				Namespace:   "default",
+				Annotations: map[string]string{betaStorageClassAnnotation: storageosSC},
+			},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-pvc", Namespace: "default"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &otherSC},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "unset-pvc", Namespace: "default"},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-class-pvc", Namespace: "default"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &noSC},
+		},
+	}
+
+	pvcVolume := func(claimName string) corev1.Volume {
+		return corev1.Volume{
+			Name: claimName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+			},
+		}
+	}
+
+	cases := []struct {
+		name              string
+		cluster           *storageoscomv1.StorageOSCluster
+		defaultSC         string
+		labels            map[string]string
+		schedulerName     string
+		volumes           []corev1.Volume
+		wantSchedulerName string
+	}{
+		{
+			name:              "storageos volume",
+			cluster:           &storageoscomv1.StorageOSCluster{},
+			volumes:           []corev1.Volume{pvcVolume("other-pvc"), pvcVolume("storageos-pvc")},
+			wantSchedulerName: storageoscluster.SchedulerName,
+		},
+		{
+			name:              "storageos volume with beta annotation",
+			cluster:           &storageoscomv1.StorageOSCluster{},
+			volumes:           []corev1.Volume{pvcVolume("beta-pvc")},
+			wantSchedulerName: storageoscluster.SchedulerName,
+		},
+		{
+			name:    "storageos ephemeral volume",
+			cluster: &storageoscomv1.StorageOSCluster{},
+			volumes: []corev1.Volume{
+				{
+					Name: "ephemeral",
+					VolumeSource: corev1.VolumeSource{
+						Ephemeral: &corev1.EphemeralVolumeSource{
+							VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{
+								Spec: corev1.PersistentVolumeClaimSpec{StorageClassName: &storageosSC},
+							},
+						},
+					},
+				},
+			},
+			wantSchedulerName: storageoscluster.SchedulerName,
+		},
+		{
+			name:              "default storageos storageclass",
+			cluster:           &storageoscomv1.StorageOSCluster{},
+			defaultSC:         storageosSC,
+			volumes:           []corev1.Volume{pvcVolume("unset-pvc")},
+			wantSchedulerName: storageoscluster.SchedulerName,
+		},
+		{
+			name:      "storageos ephemeral volume with default storageclass",
+			cluster:   &storageoscomv1.StorageOSCluster{},
+			defaultSC: storageosSC,
+			volumes: []corev1.Volume{
+				{
+					Name: "ephemeral",
+					VolumeSource: corev1.VolumeSource{
+						Ephemeral: &corev1.EphemeralVolumeSource{
+							VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{},
+						},
+					},
+				},
+			},
+			wantSchedulerName: storageoscluster.SchedulerName,
+		},
+		{
+			name:      "default other storageclass",
+			cluster:   &storageoscomv1.StorageOSCluster{},
+			defaultSC: otherSC,
+			volumes:   []corev1.Volume{pvcVolume("unset-pvc")},
+		},
+		{
+			name:    "no default storageclass",
+			cluster: &storageoscomv1.StorageOSCluster{},
+			volumes: []corev1.Volume{pvcVolume("unset-pvc")},
+		},
+		{
+			name:      "explicitly no storageclass",
+			cluster:   &storageoscomv1.StorageOSCluster{},
+			defaultSC: storageosSC,
+			volumes:   []corev1.Volume{pvcVolume("no-class-pvc")},
+		},
+		{
+			name:    "other volume",
+			cluster: &storageoscomv1.StorageOSCluster{},
+			volumes: []corev1.Volume{pvcVolume("other-pvc")},
+		},
+		{
+			name:    "missing claim",
+			cluster: &storageoscomv1.StorageOSCluster{},
+			volumes: []corev1.Volume{pvcVolume("missing-pvc")},
+		},
+		{
+			name:    "opted out",
+			cluster: &storageoscomv1.StorageOSCluster{},
+			labels:  map[string]string{SchedulerOptOutLabel: "false"},
+			volumes: []corev1.Volume{pvcVolume("storageos-pvc")},
+		},
+		{
+			name:              "custom scheduler",
+			cluster:           &storageoscomv1.StorageOSCluster{},
+			schedulerName:     "foo-scheduler",
+			volumes:           []corev1.Volume{pvcVolume("storageos-pvc")},
+			wantSchedulerName: "foo-scheduler",
+		},
+		{
+			name: "scheduler disabled",
+			cluster: &storageoscomv1.StorageOSCluster{
+				Spec: storageoscomv1.StorageOSClusterSpec{DisableScheduler: true},
+			},
+			volumes: []corev1.Volume{pvcVolume("storageos-pvc")},
+		},
+		{
+			name:    "no cluster",
+			volumes: []corev1.Volume{pvcVolume("storageos-pvc")},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			objs := []client.Object{}
+			for _, obj := range objects {
+				obj = obj.DeepCopyObject().(client.Object)
+				if obj.GetName() == tc.defaultSC {
+					if sc, ok := obj.(*storagev1.StorageClass); ok {
+						sc.SetAnnotations(map[string]string{defaultStorageClassAnnotation: "true"})
+					}
+				}
+				objs = append(objs, obj)
+			}
+			if tc.cluster != nil {
+				cluster := tc.cluster.DeepCopy()
+				cluster.SetName("storageos")
+				cluster.SetNamespace("storageos")
+				objs = append(objs, cluster)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+
+			wh := NewPodSchedulerWebhook(cl, cl)
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Labels: tc.labels},
+				Spec: corev1.PodSpec{
+					SchedulerName: tc.schedulerName,
+					Volumes:       tc.volumes,
+				},
+			}
+
+			if wh.RequireDefaulting(pod) {
+				for _, f := range wh.Default() {
+					f(context.Background(), pod)
+				}
+			}
+			assert.Equal(t, tc.wantSchedulerName, pod.Spec.SchedulerName)
+		})
+	}
+}
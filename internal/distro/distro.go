@@ -0,0 +1,164 @@
+package distro
+
+import (
+	"strings"
+)
+
+// Supported kubernetes distribution names.
+const (
+	OpenShift = "openshift"
+	Rancher   = "rancher"
+	AKS       = "aks"
+	GKE       = "gke"
+	EKS       = "eks"
+	K3s       = "k3s"
+	MicroK8s  = "microk8s"
+)
+
+const (
+	// defaultKubeletDir is the kubelet root directory used by most
+	// distributions.
+	defaultKubeletDir = "/var/lib/kubelet"
+
+	// microK8sKubeletDir is the kubelet root directory of the MicroK8s snap.
+	microK8sKubeletDir = "/var/snap/microk8s/common/var/lib/kubelet"
+
+	// storageosPriorityClass is the PriorityClass created by the operator
+	// before the installation.
+	storageosPriorityClass = "storageos"
+
+	// aksKubeSchedulerRepository is the kube-scheduler image repository
+	// published by AKS.
+	aksKubeSchedulerRepository = "mcr.microsoft.com/oss/kubernetes/kube-scheduler"
+
+	// openShiftAPIGroup is an API group only served by OpenShift.
+	openShiftAPIGroup = "config.openshift.io"
+)
+
+// Node labels set by the distributions on all the nodes.
+const (
+	aksNodeLabel      = "kubernetes.azure.com/cluster"
+	gkeNodeLabel      = "cloud.google.com/gke-nodepool"
+	eksNodeLabel      = "eks.amazonaws.com/nodegroup"
+	microK8sNodeLabel = "microk8s.io/cluster"
+)
+
+// Profile contains the defaults of a kubernetes distribution. The defaults
+// are overridden by the explicitly set cluster configuration.
+type Profile struct {
+	// Name is the name of the distribution.
+	Name string
+
+	// KubeletDir is the kubelet root directory on the nodes. The CSI paths
+	// are derived from it.
+	KubeletDir string
+
+	// SecurityContextConstraints is true when the distribution enforces
+	// OpenShift SecurityContextConstraints on the pods.
+	SecurityContextConstraints bool
+
+	// PodSecurityPolicy is true when the distribution enables the
+	// PodSecurityPolicy admission by default. The storageos pods are allowed
+	// to use a privileged PodSecurityPolicy where the API server serves them.
+	PodSecurityPolicy bool
+
+	// PriorityClassName is the priority class of the storageos pods. An empty
+	// value keeps the system critical priority classes of the manifests.
+	PriorityClassName string
+
+	// KubeSchedulerRepository is the repository of the kube-scheduler image,
+	// tagged with the kubernetes version. An empty value uses the upstream
+	// image.
+	KubeSchedulerRepository string
+}
+
+// profiles is the registry of the supported distribution profiles.
+var profiles = map[string]Profile{
+	OpenShift: {
+		Name:                       OpenShift,
+		KubeletDir:                 defaultKubeletDir,
+		SecurityContextConstraints: true,
+	},
+	Rancher: {
+		Name:       Rancher,
+		KubeletDir: defaultKubeletDir,
+		// RKE and RKE2 clusters restrict the privileged pods with
+		// PodSecurityPolicies, before kubernetes 1.25.
+		PodSecurityPolicy: true,
+	},
+	AKS: {
+		Name:                    AKS,
+		KubeletDir:              defaultKubeletDir,
+		KubeSchedulerRepository: aksKubeSchedulerRepository,
+	},
+	GKE: {
+		Name:       GKE,
+		KubeletDir: defaultKubeletDir,
+		// GKE limits the system critical priority classes to the
+		// kube-system namespace unless a matching ResourceQuota exists.
+		PriorityClassName: storageosPriorityClass,
+	},
+	EKS: {
+		Name:       EKS,
+		KubeletDir: defaultKubeletDir,
+	},
+	K3s: {
+		Name:       K3s,
+		KubeletDir: defaultKubeletDir,
+	},
+	MicroK8s: {
+		Name:       MicroK8s,
+		KubeletDir: microK8sKubeletDir,
+	},
+}
+
+// Name returns the distribution name of a k8sDistro value in the format
+// `name[-1.0]`, without the version.
+func Name(k8sDistro string) string {
+	name := strings.SplitN(k8sDistro, "-", 2)[0]
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Get returns the profile of a k8sDistro value. False is returned if the
+// distribution is unknown.
+func Get(k8sDistro string) (Profile, bool) {
+	p, ok := profiles[Name(k8sDistro)]
+	return p, ok
+}
+
+// Detect returns the name of the distribution based on the kubernetes server
+// git version, the served API groups and the labels of a node. An empty
+// string is returned if the distribution can't be detected.
+func Detect(gitVersion string, apiGroups []string, nodeLabels map[string]string) string {
+	for _, g := range apiGroups {
+		if g == openShiftAPIGroup {
+			return OpenShift
+		}
+	}
+
+	switch {
+	case strings.Contains(gitVersion, "+k3s"):
+		return K3s
+	case strings.Contains(gitVersion, "+rke2"):
+		return Rancher
+	case strings.Contains(gitVersion, "-eks-"):
+		return EKS
+	case strings.Contains(gitVersion, "-gke."):
+		return GKE
+	}
+
+	if _, ok := nodeLabels[aksNodeLabel]; ok {
+		return AKS
+	}
+	if _, ok := nodeLabels[gkeNodeLabel]; ok {
+		return GKE
+	}
+	if _, ok := nodeLabels[eksNodeLabel]; ok {
+		return EKS
+	}
+	if _, ok := nodeLabels[microK8sNodeLabel]; ok {
+		return MicroK8s
+	}
+
+	return ""
+}
@@ -0,0 +1,141 @@
+package distro
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	cases := []struct {
+		name           string
+		k8sDistro      string
+		wantOK         bool
+		wantName       string
+		wantKubeletDir string
+		wantSCC        bool
+		wantPSP        bool
+	}{
+		{
+			name:           "known distro",
+			k8sDistro:      "gke",
+			wantOK:         true,
+			wantName:       GKE,
+			wantKubeletDir: defaultKubeletDir,
+		},
+		{
+			name:           "versioned distro",
+			k8sDistro:      "openshift-4.8",
+			wantOK:         true,
+			wantName:       OpenShift,
+			wantKubeletDir: defaultKubeletDir,
+			wantSCC:        true,
+		},
+		{
+			name:           "pod security policy",
+			k8sDistro:      "rancher",
+			wantOK:         true,
+			wantName:       Rancher,
+			wantKubeletDir: defaultKubeletDir,
+			wantPSP:        true,
+		},
+		{
+			name:           "mixed case distro",
+			k8sDistro:      "MicroK8s",
+			wantOK:         true,
+			wantName:       MicroK8s,
+			wantKubeletDir: microK8sKubeletDir,
+		},
+		{
+			name:      "unknown distro",
+			k8sDistro: "foo-1.0",
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			p, ok := Get(tc.k8sDistro)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantName, p.Name)
+			assert.Equal(t, tc.wantKubeletDir, p.KubeletDir)
+			assert.Equal(t, tc.wantSCC, p.SecurityContextConstraints)
+			assert.Equal(t, tc.wantPSP, p.PodSecurityPolicy)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name       string
+		gitVersion string
+		apiGroups  []string
+		nodeLabels map[string]string
+		want       string
+	}{
+		{
+			name:       "openshift",
+			gitVersion: "v1.24.0+9546431",
+			apiGroups:  []string{"apps", "config.openshift.io"},
+			want:       OpenShift,
+		},
+		{
+			name:       "k3s",
+			gitVersion: "v1.27.4+k3s1",
+			want:       K3s,
+		},
+		{
+			name:       "rke2",
+			gitVersion: "v1.26.7+rke2r1",
+			want:       Rancher,
+		},
+		{
+			name:       "eks",
+			gitVersion: "v1.27.4-eks-2d98532",
+			want:       EKS,
+		},
+		{
+			name:       "gke",
+			gitVersion: "v1.27.3-gke.100",
+			want:       GKE,
+		},
+		{
+			name:       "aks node label",
+			gitVersion: "v1.27.3",
+			nodeLabels: map[string]string{"kubernetes.azure.com/cluster": "MC_foo"},
+			want:       AKS,
+		},
+		{
+			name:       "microk8s node label",
+			gitVersion: "v1.27.4",
+			nodeLabels: map[string]string{"microk8s.io/cluster": "true"},
+			want:       MicroK8s,
+		},
+		{
+			name:       "unknown",
+			gitVersion: "v1.27.4",
+			apiGroups:  []string{"apps"},
+			nodeLabels: map[string]string{"kubernetes.io/os": "linux"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, Detect(tc.gitVersion, tc.apiGroups, tc.nodeLabels))
+		})
+	}
+}
+
+func TestProfilesKubeletDir(t *testing.T) {
+	// The CSI registration, plugin and registrar socket directories are
+	// derived from the kubelet directory, all the profiles must set it.
+	for name, p := range profiles {
+		assert.Equal(t, name, p.Name)
+		assert.True(t, path.IsAbs(p.KubeletDir), name)
+	}
+}
@@ -0,0 +1,46 @@
+// Package features contains the feature gates of the operator, set from the
+// operator configuration at startup.
+package features
+
+import (
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/component-base/featuregate"
+)
+
+const (
+	// LeaderReadiness requires the operator to be the elected leader to
+	// report ready. The standby replicas are not ready.
+	LeaderReadiness featuregate.Feature = "LeaderReadiness"
+)
+
+// defaultFeatures are all the features of the operator with their defaults.
+var defaultFeatures = map[featuregate.Feature]featuregate.FeatureSpec{
+	LeaderReadiness: {Default: true, PreRelease: featuregate.Beta},
+}
+
+// DefaultFeatureGate is the feature gate of the operator.
+var DefaultFeatureGate featuregate.MutableFeatureGate = featuregate.NewFeatureGate()
+
+func init() {
+	utilruntime.Must(DefaultFeatureGate.Add(defaultFeatures))
+}
+
+// Set sets the feature gates from the given map of feature names. Unknown
+// features fail.
+func Set(gates map[string]bool) error {
+	return DefaultFeatureGate.SetFromMap(gates)
+}
+
+// Enabled returns if the given feature is enabled.
+func Enabled(f featuregate.Feature) bool {
+	return DefaultFeatureGate.Enabled(f)
+}
+
+// All returns all the features of the operator with their state.
+func All() map[string]bool {
+	all := map[string]bool{}
+	for f := range defaultFeatures {
+		all[string(f)] = Enabled(f)
+	}
+	return all
+}
@@ -0,0 +1,22 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	defer func() {
+		assert.Nil(t, Set(map[string]bool{string(LeaderReadiness): true}))
+	}()
+
+	assert.True(t, Enabled(LeaderReadiness))
+	assert.Equal(t, map[string]bool{"LeaderReadiness": true}, All())
+
+	assert.Nil(t, Set(map[string]bool{"LeaderReadiness": false}))
+	assert.False(t, Enabled(LeaderReadiness))
+	assert.Equal(t, map[string]bool{"LeaderReadiness": false}, All())
+
+	assert.NotNil(t, Set(map[string]bool{"Foo": true}))
+}
@@ -0,0 +1,167 @@
+// Package health contains the health and readiness checks of the operator,
+// served by the controller-runtime manager health probe endpoints.
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// checkTimeout is the maximum duration of a check that waits on another
+// component.
+const checkTimeout = time.Second
+
+// WebhookTLSCheck returns a checker that succeeds when the webhook server at
+// the given host and port completes a TLS handshake. The webhook server
+// defaults are used for an empty host and port. The serving certificate
+// is self-signed by the certificate manager and isn't verified here, its
+// validity is checked by CertValidityCheck.
+func WebhookTLSCheck(host string, port int) healthz.Checker {
+	if host == "" {
+		host = "localhost"
+	}
+	if port <= 0 {
+		port = webhook.DefaultPort
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	return func(_ *http.Request) error {
+		dialer := &net.Dialer{Timeout: checkTimeout}
+		conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			return fmt.Errorf("webhook server TLS handshake failed: %w", err)
+		}
+		return conn.Close()
+	}
+}
+
+// CertValidityCheck returns a checker that succeeds when the PEM encoded
+// certificate at the given path is within its validity period.
+func CertValidityCheck(certPath string) healthz.Checker {
+	return func(_ *http.Request) error {
+		cert, err := ReadCertificate(certPath)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if now.Before(cert.NotBefore) {
+			return fmt.Errorf("certificate %q not valid before %s", certPath, cert.NotBefore)
+		}
+		if now.After(cert.NotAfter) {
+			return fmt.Errorf("certificate %q expired at %s", certPath, cert.NotAfter)
+		}
+		return nil
+	}
+}
+
+// CacheSyncCheck returns a checker that succeeds when the informers of the
+// given cache have synced.
+func CacheSyncCheck(c cache.Cache) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), checkTimeout)
+		defer cancel()
+		if !c.WaitForCacheSync(ctx) {
+			return errors.New("informer caches not synced")
+		}
+		return nil
+	}
+}
+
+// LeaderCheck returns a checker that succeeds when the given channel is
+// closed, once the manager is elected leader. Managers without leader
+// election close the channel at start.
+func LeaderCheck(elected <-chan struct{}) healthz.Checker {
+	return func(_ *http.Request) error {
+		select {
+		case <-elected:
+			return nil
+		default:
+			return errors.New("not elected leader")
+		}
+	}
+}
+
+// ReadCertificate returns the first certificate in the PEM encoded file at
+// the given path.
+func ReadCertificate(certPath string) (*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %q", certPath)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// ReconcileTracker tracks the in-flight reconciles to detect a wedged
+// reconcile loop. Reconciles that don't return within the timeout fail the
+// liveness check.
+type ReconcileTracker struct {
+	timeout time.Duration
+
+	mu       sync.Mutex
+	nextID   uint64
+	inFlight map[uint64]time.Time
+
+	// now returns the current time, overridden in tests.
+	now func() time.Time
+}
+
+// NewReconcileTracker returns a ReconcileTracker with the given reconcile
+// timeout.
+func NewReconcileTracker(timeout time.Duration) *ReconcileTracker {
+	return &ReconcileTracker{
+		timeout:  timeout,
+		inFlight: map[uint64]time.Time{},
+		now:      time.Now,
+	}
+}
+
+// Begin records the start of a reconcile. The returned function must be
+// called when the reconcile returns.
+func (t *ReconcileTracker) Begin() func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.nextID
+	t.nextID++
+	t.inFlight[id] = t.now()
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.inFlight, id)
+	}
+}
+
+// Check fails when a reconcile has been in-flight for longer than the
+// timeout. An idle reconcile loop is healthy.
+func (t *ReconcileTracker) Check(_ *http.Request) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, start := range t.inFlight {
+		if elapsed := now.Sub(start); elapsed > t.timeout {
+			return fmt.Errorf("reconcile running for %s, exceeding %s", elapsed.Round(time.Second), t.timeout)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package health
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCert writes a self-signed PEM encoded certificate with the given
+// validity period and returns its path.
+func writeCert(t *testing.T, dir string, notBefore, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "foo"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	f, err := ioutil.TempFile(dir, "tls-*.crt")
+	assert.Nil(t, err)
+	defer f.Close()
+	assert.Nil(t, pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	return f.Name()
+}
+
+func TestCertValidityCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	invalidPath := filepath.Join(dir, "invalid.crt")
+	assert.Nil(t, ioutil.WriteFile(invalidPath, []byte("foo"), 0600))
+
+	cases := []struct {
+		name     string
+		certPath string
+		wantErr  bool
+	}{
+		{
+			name:     "valid",
+			certPath: writeCert(t, dir, now.Add(-time.Hour), now.Add(time.Hour)),
+		},
+		{
+			name:     "expired",
+			certPath: writeCert(t, dir, now.Add(-2*time.Hour), now.Add(-time.Hour)),
+			wantErr:  true,
+		},
+		{
+			name:     "not yet valid",
+			certPath: writeCert(t, dir, now.Add(time.Hour), now.Add(2*time.Hour)),
+			wantErr:  true,
+		},
+		{
+			name:     "invalid",
+			certPath: invalidPath,
+			wantErr:  true,
+		},
+		{
+			name:     "missing",
+			certPath: filepath.Join(dir, "missing.crt"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := CertValidityCheck(tc.certPath)(nil)
+			assert.Equal(t, tc.wantErr, err != nil, "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestWebhookTLSCheck(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.Nil(t, err)
+	portNum, err := strconv.Atoi(port)
+	assert.Nil(t, err)
+	assert.Nil(t, WebhookTLSCheck(host, portNum)(nil))
+
+	// A plain TCP listener fails the handshake.
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	host, port, err = net.SplitHostPort(plain.Listener.Addr().String())
+	assert.Nil(t, err)
+	portNum, err = strconv.Atoi(port)
+	assert.Nil(t, err)
+	assert.NotNil(t, WebhookTLSCheck(host, portNum)(nil))
+}
+
+func TestLeaderCheck(t *testing.T) {
+	elected := make(chan struct{})
+	check := LeaderCheck(elected)
+	assert.NotNil(t, check(nil))
+
+	close(elected)
+	assert.Nil(t, check(nil))
+}
+
+func TestReconcileTracker(t *testing.T) {
+	now := time.Now()
+	tracker := NewReconcileTracker(time.Minute)
+	tracker.now = func() time.Time { return now }
+
+	// Idle.
+	assert.Nil(t, tracker.Check(nil))
+
+	done := tracker.Begin()
+	now = now.Add(30 * time.Second)
+	assert.Nil(t, tracker.Check(nil))
+
+	// Wedged.
+	now = now.Add(time.Minute)
+	assert.NotNil(t, tracker.Check(nil))
+
+	// A new reconcile doesn't hide the wedged one.
+	doneNext := tracker.Begin()
+	assert.NotNil(t, tracker.Check(nil))
+	doneNext()
+
+	done()
+	assert.Nil(t, tracker.Check(nil))
+}
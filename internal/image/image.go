@@ -1,6 +1,8 @@
 package image
 
 import (
+	"strings"
+
 	kustomizeimage "sigs.k8s.io/kustomize/api/image"
 	kustomizetypes "sigs.k8s.io/kustomize/api/types"
 )
@@ -55,3 +57,23 @@ func GetKustomizeImageList(images NamedImages) []kustomizetypes.Image {
 
 	return kImages
 }
+
+// WithRegistry returns the given image with its registry replaced by the
+// given registry. Images without a registry, from Docker Hub, are prefixed
+// with the registry.
+func WithRegistry(image, registry string) string {
+	if image == "" || registry == "" {
+		return image
+	}
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && isRegistry(parts[0]) {
+		return registry + "/" + parts[1]
+	}
+	return registry + "/" + image
+}
+
+// isRegistry returns if the first component of an image name is a registry
+// host, like docker does: a host with a domain or port, or localhost.
+func isRegistry(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost"
+}
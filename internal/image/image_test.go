@@ -93,3 +93,47 @@ func TestGetKustomizeImageList(t *testing.T) {
 		})
 	}
 }
+
+func TestWithRegistry(t *testing.T) {
+	cases := []struct {
+		name     string
+		image    string
+		registry string
+		want     string
+	}{
+		{
+			name:     "docker hub image",
+			image:    "storageos/node:v2.4.0",
+			registry: "registry.example.com",
+			want:     "registry.example.com/storageos/node:v2.4.0",
+		},
+		{
+			name:     "custom registry",
+			image:    "quay.io/k8scsi/csi-attacher:v3.1.0",
+			registry: "registry.example.com:5000/mirror",
+			want:     "registry.example.com:5000/mirror/k8scsi/csi-attacher:v3.1.0",
+		},
+		{
+			name:     "localhost registry",
+			image:    "localhost/foo@sha256:25a0d4",
+			registry: "registry.example.com",
+			want:     "registry.example.com/foo@sha256:25a0d4",
+		},
+		{
+			name:  "no registry override",
+			image: "storageos/node:v2.4.0",
+			want:  "storageos/node:v2.4.0",
+		},
+		{
+			name:     "empty",
+			registry: "registry.example.com",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, WithRegistry(tc.image, tc.registry))
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// Package metrics contains the prometheus metrics of the operator. The metrics
+// are registered with the controller-runtime metrics registry and served with
+// the controller-runtime metrics.
+package metrics
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+	"github.com/storageos/operator/internal/health"
+)
+
+// metricsNamespace is the namespace of all the operator metrics.
+const metricsNamespace = "storageos_operator"
+
+// Metric labels.
+const (
+	clusterLabel   = "cluster"
+	namespaceLabel = "namespace"
+	operandLabel   = "operand"
+	operationLabel = "operation"
+	phaseLabel     = "phase"
+	featureLabel   = "feature"
+
+	// Operator configuration labels.
+	executionStrategyLabel = "execution_strategy"
+	channelLabel           = "channel"
+	watchNamespacesLabel   = "watch_namespaces"
+	imageRegistryLabel     = "image_registry"
+)
+
+// clusterPhases are all the phases of a cluster, reported by the phase
+// gauge.
+var clusterPhases = []string{
+	storageoscomv1.ClusterPhasePending,
+	storageoscomv1.ClusterPhaseCreating,
+	storageoscomv1.ClusterPhaseRunning,
+	storageoscomv1.ClusterPhaseDegraded,
+	storageoscomv1.ClusterPhaseUpgrading,
+	storageoscomv1.ClusterPhaseTerminating,
+}
+
+var (
+	// OperandEnsureDuration is the duration of the operand Ensure calls.
+	OperandEnsureDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "operand_ensure_duration_seconds",
+			Help:      "Duration of the operand ensure operations in seconds.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{clusterLabel, namespaceLabel, operandLabel},
+	)
+
+	// OperandEnsureErrors is the number of failed operand Ensure calls.
+	OperandEnsureErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "operand_ensure_errors_total",
+			Help:      "Total number of failed operand ensure operations.",
+		},
+		[]string{clusterLabel, namespaceLabel, operandLabel},
+	)
+
+	// ControlPlaneRequestDuration is the latency of the StorageOS
+	// control-plane API requests.
+	ControlPlaneRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "control_plane_request_duration_seconds",
+			Help:      "Duration of the StorageOS control-plane API requests in seconds.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{operationLabel},
+	)
+
+	// ControlPlaneRequestErrors is the number of failed StorageOS
+	// control-plane API requests.
+	ControlPlaneRequestErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "control_plane_request_errors_total",
+			Help:      "Total number of failed StorageOS control-plane API requests.",
+		},
+		[]string{operationLabel},
+	)
+
+	// ClusterPhase is 1 for the current phase of a cluster and 0 for the
+	// other phases.
+	ClusterPhase = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cluster_phase",
+			Help:      "Phase of the StorageOS cluster, 1 for the current phase.",
+		},
+		[]string{clusterLabel, namespaceLabel, phaseLabel},
+	)
+
+	// ClusterNodesReady is the number of ready StorageOS node pods.
+	ClusterNodesReady = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cluster_nodes_ready",
+			Help:      "Number of ready StorageOS node pods.",
+		},
+		[]string{clusterLabel, namespaceLabel},
+	)
+
+	// ClusterNodesDesired is the number of desired StorageOS node pods.
+	ClusterNodesDesired = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cluster_nodes_desired",
+			Help:      "Number of desired StorageOS node pods.",
+		},
+		[]string{clusterLabel, namespaceLabel},
+	)
+
+	// ClusterUpgradeProgress is the ratio of the StorageOS node pods running
+	// the latest node configuration.
+	ClusterUpgradeProgress = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cluster_upgrade_progress_ratio",
+			Help:      "Ratio of the StorageOS node pods updated to the latest configuration.",
+		},
+		[]string{clusterLabel, namespaceLabel},
+	)
+
+	// OperatorConfigInfo reports the operator configuration options in its
+	// labels, with a constant value of 1.
+	OperatorConfigInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "config_info",
+			Help:      "Operator configuration options, with a constant value of 1.",
+		},
+		[]string{executionStrategyLabel, channelLabel, watchNamespacesLabel, imageRegistryLabel},
+	)
+
+	// FeatureEnabled reports the state of the operator feature gates.
+	FeatureEnabled = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "feature_enabled",
+			Help:      "Whether an operator feature gate is enabled (1) or disabled (0).",
+		},
+		[]string{featureLabel},
+	)
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		OperandEnsureDuration,
+		OperandEnsureErrors,
+		ControlPlaneRequestDuration,
+		ControlPlaneRequestErrors,
+		ClusterPhase,
+		ClusterNodesReady,
+		ClusterNodesDesired,
+		ClusterUpgradeProgress,
+		OperatorConfigInfo,
+		FeatureEnabled,
+	)
+}
+
+// ObserveOperandEnsure records the duration and the error of an operand
+// Ensure call that started at the given time.
+func ObserveOperandEnsure(cluster, namespace, operand string, start time.Time, err error) {
+	OperandEnsureDuration.WithLabelValues(cluster, namespace, operand).Observe(time.Since(start).Seconds())
+	if err != nil {
+		OperandEnsureErrors.WithLabelValues(cluster, namespace, operand).Inc()
+	}
+}
+
+// ObserveControlPlaneRequest records the duration and the error of a
+// control-plane API request that started at the given time.
+func ObserveControlPlaneRequest(operation string, start time.Time, err error) {
+	ControlPlaneRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ControlPlaneRequestErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// SetClusterPhase sets the phase gauge of a cluster.
+func SetClusterPhase(cluster, namespace, phase string) {
+	for _, p := range clusterPhases {
+		value := 0.0
+		if p == phase {
+			value = 1
+		}
+		ClusterPhase.WithLabelValues(cluster, namespace, p).Set(value)
+	}
+}
+
+// SetClusterNodes sets the node gauges of a cluster. The upgrade progress is
+// the ratio of updated to desired node pods.
+func SetClusterNodes(cluster, namespace string, ready, desired, updated int32) {
+	ClusterNodesReady.WithLabelValues(cluster, namespace).Set(float64(ready))
+	ClusterNodesDesired.WithLabelValues(cluster, namespace).Set(float64(desired))
+
+	progress := 1.0
+	if desired > 0 {
+		progress = float64(updated) / float64(desired)
+	}
+	ClusterUpgradeProgress.WithLabelValues(cluster, namespace).Set(progress)
+}
+
+// DeleteClusterMetrics removes the gauges of a cluster.
+func DeleteClusterMetrics(cluster, namespace string) {
+	for _, p := range clusterPhases {
+		ClusterPhase.DeleteLabelValues(cluster, namespace, p)
+	}
+	ClusterNodesReady.DeleteLabelValues(cluster, namespace)
+	ClusterNodesDesired.DeleteLabelValues(cluster, namespace)
+	ClusterUpgradeProgress.DeleteLabelValues(cluster, namespace)
+}
+
+// SetOperatorConfig sets the operator configuration info and feature gate
+// gauges. The watched namespaces are comma separated, empty for all the
+// namespaces.
+func SetOperatorConfig(executionStrategy, channel string, watchNamespaces []string, imageRegistry string, features map[string]bool) {
+	OperatorConfigInfo.Reset()
+	OperatorConfigInfo.WithLabelValues(executionStrategy, channel, strings.Join(watchNamespaces, ","), imageRegistry).Set(1)
+
+	for feature, enabled := range features {
+		value := 0.0
+		if enabled {
+			value = 1
+		}
+		FeatureEnabled.WithLabelValues(feature).Set(value)
+	}
+}
+
+// RegisterWebhookCertExpiry registers a gauge with the expiry time of the
+// webhook serving certificate at the given path. The certificate is read on
+// every collection because it's rotated by the certificate manager.
+func RegisterWebhookCertExpiry(certPath string) error {
+	return ctrlmetrics.Registry.Register(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "webhook_cert_expiry_timestamp_seconds",
+			Help:      "Expiry time of the webhook serving certificate in seconds since epoch, 0 if unknown.",
+		},
+		func() float64 {
+			expiry, err := getCertExpiry(certPath)
+			if err != nil {
+				return 0
+			}
+			return float64(expiry.Unix())
+		},
+	))
+}
+
+// getCertExpiry returns the expiry time of the first certificate in the PEM
+// encoded file at the given path.
+func getCertExpiry(certPath string) (time.Time, error) {
+	cert, err := health.ReadCertificate(certPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	storageoscomv1 "github.com/storageos/operator/apis/v1"
+)
+
+func TestSetClusterPhase(t *testing.T) {
+	SetClusterPhase("foo", "some-ns", storageoscomv1.ClusterPhaseCreating)
+	SetClusterPhase("foo", "some-ns", storageoscomv1.ClusterPhaseRunning)
+
+	for _, phase := range clusterPhases {
+		want := 0.0
+		if phase == storageoscomv1.ClusterPhaseRunning {
+			want = 1
+		}
+		assert.Equal(t, want, testutil.ToFloat64(ClusterPhase.WithLabelValues("foo", "some-ns", phase)), phase)
+	}
+
+	DeleteClusterMetrics("foo", "some-ns")
+	assert.Equal(t, 0, testutil.CollectAndCount(ClusterPhase))
+}
+
+func TestSetClusterNodes(t *testing.T) {
+	cases := []struct {
+		name         string
+		ready        int32
+		desired      int32
+		updated      int32
+		wantProgress float64
+	}{
+		{
+			name:         "no nodes",
+			wantProgress: 1,
+		},
+		{
+			name:         "upgrading",
+			ready:        3,
+			desired:      4,
+			updated:      1,
+			wantProgress: 0.25,
+		},
+		{
+			name:         "upgraded",
+			ready:        4,
+			desired:      4,
+			updated:      4,
+			wantProgress: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			defer DeleteClusterMetrics("foo", "some-ns")
+
+			SetClusterNodes("foo", "some-ns", tc.ready, tc.desired, tc.updated)
+			assert.Equal(t, float64(tc.ready), testutil.ToFloat64(ClusterNodesReady.WithLabelValues("foo", "some-ns")))
+			assert.Equal(t, float64(tc.desired), testutil.ToFloat64(ClusterNodesDesired.WithLabelValues("foo", "some-ns")))
+			assert.Equal(t, tc.wantProgress, testutil.ToFloat64(ClusterUpgradeProgress.WithLabelValues("foo", "some-ns")))
+		})
+	}
+}
+
+func TestObserveOperandEnsure(t *testing.T) {
+	ObserveOperandEnsure("foo", "some-ns", "node-operand", time.Now(), nil)
+	ObserveOperandEnsure("foo", "some-ns", "node-operand", time.Now(), errors.New("some error"))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(OperandEnsureErrors.WithLabelValues("foo", "some-ns", "node-operand")))
+	assert.Equal(t, 1, testutil.CollectAndCount(OperandEnsureDuration))
+}
+
+func TestGetCertExpiry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "foo"},
+		NotBefore:    time.Now(),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	certPath := filepath.Join(dir, "tls.crt")
+	assert.Nil(t, ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+
+	expiry, err := getCertExpiry(certPath)
+	assert.Nil(t, err)
+	assert.True(t, notAfter.Equal(expiry))
+
+	invalidPath := filepath.Join(dir, "invalid.crt")
+	assert.Nil(t, ioutil.WriteFile(invalidPath, []byte("foo"), 0600))
+	_, err = getCertExpiry(invalidPath)
+	assert.NotNil(t, err)
+
+	_, err = getCertExpiry(filepath.Join(dir, "missing.crt"))
+	assert.NotNil(t, err)
+}
+
+func TestSetOperatorConfig(t *testing.T) {
+	SetOperatorConfig("Serial", "stable", []string{"foo", "bar"}, "registry.example.com", map[string]bool{"Foo": true, "Bar": false})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(OperatorConfigInfo.WithLabelValues("Serial", "stable", "foo,bar", "registry.example.com")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(FeatureEnabled.WithLabelValues("Foo")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(FeatureEnabled.WithLabelValues("Bar")))
+
+	// The previous configuration is replaced.
+	SetOperatorConfig("Parallel", "stable", nil, "", nil)
+	assert.Equal(t, 1, testutil.CollectAndCount(OperatorConfigInfo))
+}
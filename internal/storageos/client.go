@@ -10,6 +10,8 @@ import (
 	"time"
 
 	api "github.com/storageos/go-api/v2"
+
+	"github.com/storageos/operator/internal/metrics"
 )
 
 //go:generate ../../bin/mockgen -destination=mocks/mock_control_plane.go -package=mocks github.com/storageos/operator/internal/storageos ControlPlane
@@ -47,6 +49,13 @@ const (
 	LogLevelDebug = "debug"
 )
 
+// Operation names of the API requests, used in the request metrics.
+const (
+	authenticateOperation  = "authenticate"
+	getClusterOperation    = "get_cluster"
+	updateClusterOperation = "update_cluster"
+)
+
 var (
 	// ErrNoAuthToken is returned when the API client did not get an error
 	// during authentication but no valid auth token was returned.
@@ -113,10 +122,12 @@ func (c *Client) Authenticate(ctx context.Context, username, password string) er
 	defer cancel()
 
 	// Initial basic auth to retrieve the jwt token.
+	start := time.Now()
 	_, resp, err := c.api.AuthenticateUser(ctx, api.AuthUserData{
 		Username: username,
 		Password: password,
 	})
+	metrics.ObserveControlPlaneRequest(authenticateOperation, start, err)
 	if err != nil {
 		return api.MapAPIError(err, resp)
 	}
@@ -3,8 +3,11 @@ package storageos
 import (
 	"context"
 	"reflect"
+	"time"
 
 	api "github.com/storageos/go-api/v2"
+
+	"github.com/storageos/operator/internal/metrics"
 )
 
 // Cluster is the configuration of a StorageOS cluster.
@@ -28,7 +31,9 @@ func (c *Cluster) IsEqual(b *Cluster) bool {
 func (c *Client) GetCluster(ctx context.Context) (*Cluster, error) {
 	ctx = c.AddToken(ctx)
 
+	start := time.Now()
 	cluster, resp, err := c.api.GetCluster(ctx)
+	metrics.ObserveControlPlaneRequest(getClusterOperation, start, err)
 	if err != nil {
 		return nil, api.MapAPIError(err, resp)
 	}
@@ -54,7 +59,9 @@ func (c *Client) UpdateCluster(ctx context.Context, cluster *Cluster) error {
 		LogFormat:             api.LogFormat(cluster.LogFormat),
 		Version:               cluster.Version,
 	}
+	start := time.Now()
 	_, resp, err := c.api.UpdateCluster(ctx, data, &api.UpdateClusterOpts{})
+	metrics.ObserveControlPlaneRequest(updateClusterOperation, start, err)
 	if err != nil {
 		return api.MapAPIError(err, resp)
 	}
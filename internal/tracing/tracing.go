@@ -0,0 +1,150 @@
+// Package tracing sets up the opentelemetry tracer provider of the operator
+// with the exporter selected in the operator configuration.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/darkowlzz/operator-toolkit/telemetry/export"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlphttp"
+	"go.opentelemetry.io/otel/exporters/stdout"
+	"go.opentelemetry.io/otel/exporters/trace/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+
+	configstorageoscomv1 "github.com/storageos/operator/apis/config.storageos.com/v1"
+)
+
+// shutdownTimeout is the maximum duration of the exporter flush on shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// Shutdown flushes and stops the exporter.
+type Shutdown func() error
+
+// Setup registers a global tracer provider exporting the traces of the given
+// service with the configured exporter. The returned Shutdown must be called
+// before exiting to flush the pending spans.
+func Setup(ctx context.Context, serviceName string, config configstorageoscomv1.TracingConfig) (Shutdown, error) {
+	switch config.Exporter {
+	case "":
+		// Keep the environment variable based Jaeger exporter when no
+		// exporter is configured.
+		shutdown, err := export.InstallJaegerExporter(serviceName)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			shutdown()
+			return nil
+		}, nil
+	case configstorageoscomv1.TracingExporterNone:
+		return func() error { return nil }, nil
+	}
+
+	sampler, err := getSampler(config.SamplingRatio)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s exporter: %w", config.Exporter, err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(resource.NewWithAttributes(getResourceAttributes(serviceName, config)...)),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func() error {
+		// New context, do not make the operator hang on shutdown.
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return tp.Shutdown(ctx)
+	}, nil
+}
+
+// newExporter returns the span exporter of the configured exporter type.
+func newExporter(ctx context.Context, config configstorageoscomv1.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case configstorageoscomv1.TracingExporterOTLPGRPC:
+		opts := []otlpgrpc.Option{}
+		if config.Endpoint != "" {
+			opts = append(opts, otlpgrpc.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlpgrpc.WithInsecure())
+		}
+		return otlp.NewExporter(ctx, otlpgrpc.NewDriver(opts...))
+	case configstorageoscomv1.TracingExporterOTLPHTTP:
+		opts := []otlphttp.Option{}
+		if config.Endpoint != "" {
+			opts = append(opts, otlphttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlphttp.WithInsecure())
+		}
+		return otlp.NewExporter(ctx, otlphttp.NewDriver(opts...))
+	case configstorageoscomv1.TracingExporterJaeger:
+		opts := []jaeger.CollectorEndpointOption{}
+		if config.Endpoint != "" {
+			opts = append(opts, jaeger.WithEndpoint(config.Endpoint))
+		}
+		return jaeger.NewRawExporter(jaeger.WithCollectorEndpoint(opts...))
+	case configstorageoscomv1.TracingExporterStdout:
+		return stdout.NewExporter(stdout.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", config.Exporter)
+	}
+}
+
+// getSampler returns a parent based sampler, sampling the root spans with
+// the given ratio. An empty ratio samples all the traces.
+func getSampler(ratio string) (sdktrace.Sampler, error) {
+	if ratio == "" {
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	}
+	fraction, err := strconv.ParseFloat(ratio, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sampling ratio %q: %w", ratio, err)
+	}
+	if fraction < 0 || fraction > 1 {
+		return nil, fmt.Errorf("invalid sampling ratio %q: must be between 0 and 1", ratio)
+	}
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(fraction)), nil
+}
+
+// getResourceAttributes returns the trace resource attributes, sorted by key.
+// The service name can't be overridden.
+func getResourceAttributes(serviceName string, config configstorageoscomv1.TracingConfig) []attribute.KeyValue {
+	keys := make([]string, 0, len(config.ResourceAttributes))
+	for k := range config.ResourceAttributes {
+		if k == string(semconv.ServiceNameKey) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(serviceName),
+		attribute.String("exporter", string(config.Exporter)),
+	}
+	for _, k := range keys {
+		attrs = append(attrs, attribute.String(k, config.ResourceAttributes[k]))
+	}
+	return attrs
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+
+	configstorageoscomv1 "github.com/storageos/operator/apis/config.storageos.com/v1"
+)
+
+func TestSetup(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  configstorageoscomv1.TracingConfig
+		wantErr bool
+	}{
+		{
+			name:   "default",
+			config: configstorageoscomv1.TracingConfig{},
+		},
+		{
+			name:   "none",
+			config: configstorageoscomv1.TracingConfig{Exporter: configstorageoscomv1.TracingExporterNone},
+		},
+		{
+			name: "stdout",
+			config: configstorageoscomv1.TracingConfig{
+				Exporter:      configstorageoscomv1.TracingExporterStdout,
+				SamplingRatio: "0.5",
+			},
+		},
+		{
+			name: "invalid sampling ratio",
+			config: configstorageoscomv1.TracingConfig{
+				Exporter:      configstorageoscomv1.TracingExporterStdout,
+				SamplingRatio: "foo",
+			},
+			wantErr: true,
+		},
+		{
+			name: "sampling ratio out of range",
+			config: configstorageoscomv1.TracingConfig{
+				Exporter:      configstorageoscomv1.TracingExporterStdout,
+				SamplingRatio: "1.5",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown exporter",
+			config:  configstorageoscomv1.TracingConfig{Exporter: "foo"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			shutdown, err := Setup(context.TODO(), "foo", tc.config)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Nil(t, shutdown())
+		})
+	}
+}
+
+func TestGetResourceAttributes(t *testing.T) {
+	config := configstorageoscomv1.TracingConfig{
+		Exporter: configstorageoscomv1.TracingExporterOTLPGRPC,
+		ResourceAttributes: map[string]string{
+			"service.name": "bar",
+			"k8s.cluster":  "baz",
+			"env":          "prod",
+		},
+	}
+
+	want := []attribute.KeyValue{
+		attribute.String("service.name", "foo"),
+		attribute.String("exporter", "otlp-grpc"),
+		attribute.String("env", "prod"),
+		attribute.String("k8s.cluster", "baz"),
+	}
+	assert.Equal(t, want, getResourceAttributes("foo", config))
+}
@@ -18,6 +18,7 @@ const (
 	// Field names in volume mounts.
 	volMountPath        = "mountPath"
 	volMountPropagation = "mountPropagation"
+	volMountReadOnly    = "readOnly"
 
 	// Field name of resource requirements.
 	resourcesField = "resources"
@@ -27,6 +28,15 @@ const (
 
 	// Field name of node selector.
 	nodeSelectorField = "requiredDuringSchedulingIgnoredDuringExecution"
+
+	// Field name of pod anti-affinity.
+	podAntiAffinityField = "podAntiAffinity"
+
+	// Field name of topology spread constraints.
+	topologySpreadConstraintsField = "topologySpreadConstraints"
+
+	// Field name of priority class name.
+	priorityClassNameField = "priorityClassName"
 )
 
 // goToRNode converts any go type into a kyaml RNode.
@@ -87,6 +97,17 @@ func SetScalarNodeStringValueFunc(valField string, value string, path ...string)
 	return SetScalarNodeFunc(valField, kyaml.NewScalarRNode(value), path...)
 }
 
+// RemoveFieldFunc removes a field at the given path. Nothing is done if the
+// path or the field doesn't exist.
+func RemoveFieldFunc(field string, path ...string) transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		return obj.PipeE(
+			kyaml.Lookup(path...),
+			kyaml.Clear(field),
+		)
+	}
+}
+
 // SetMetadataNameFunc sets the metadata name of a given resource.
 func SetMetadataNameFunc(name string) transform.TransformFunc {
 	return SetScalarNodeStringValueFunc("name", name, "metadata")
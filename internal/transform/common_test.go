@@ -39,3 +39,69 @@ spec:
 	assert.Nil(t, err)
 	assert.Equal(t, wantName, strings.TrimSpace(str))
 }
+
+func TestRemoveFieldFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: fast
+parameters:
+  csi.storage.k8s.io/secret-name: storageos-api
+  csi.storage.k8s.io/fstype: ext4
+provisioner: csi.storageos.com
+`)
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name  string
+		field string
+		path  []string
+		want  string
+	}{
+		{
+			name:  "remove field",
+			field: "csi.storage.k8s.io/secret-name",
+			path:  []string{"parameters"},
+			want: `
+apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: fast
+parameters:
+  csi.storage.k8s.io/fstype: ext4
+provisioner: csi.storageos.com
+`,
+		},
+		{
+			name:  "missing path",
+			field: "foo",
+			path:  []string{"spec"},
+			want: `
+apiVersion: storage.k8s.io/v1
+kind: StorageClass
+metadata:
+  name: fast
+parameters:
+  csi.storage.k8s.io/secret-name: storageos-api
+  csi.storage.k8s.io/fstype: ext4
+provisioner: csi.storageos.com
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			obj := testObj.Copy()
+
+			tf := RemoveFieldFunc(tc.field, tc.path...)
+			err = tf(obj)
+			assert.Nil(t, err)
+
+			gotStr, err := obj.String()
+			assert.Nil(t, err)
+			assert.Equal(t, strings.TrimSpace(tc.want), strings.TrimSpace(gotStr))
+		})
+	}
+}
@@ -2,13 +2,14 @@ package transform
 
 import (
 	"github.com/darkowlzz/operator-toolkit/declarative/transform"
+	corev1 "k8s.io/api/core/v1"
 	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 // SetEnvVarStringFunc sets a string value environment variable for a given
 // container path.
 func SetEnvVarStringFunc(val string, path ...string) transform.TransformFunc {
-	return SetScalarNodeFunc(envVarValue, kyaml.NewScalarRNode(val), path...)
+	return SetScalarNodeFunc(envVarValue, kyaml.NewStringRNode(val), path...)
 }
 
 // SetEnvVarValueFromSecretFunc sets a valueFrom secretKeyRef environment
@@ -38,3 +39,17 @@ func SetEnvVarValueFromFieldFunc(fieldPath string, path ...string) transform.Tra
 		return tf(obj)
 	}
 }
+
+// SetEnvVarValueFromFunc sets a valueFrom environment variable with the given
+// env var source for a given container path.
+func SetEnvVarValueFromFunc(source corev1.EnvVarSource, path ...string) transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		// Construct env var source RNode.
+		envVarSrc, err := goToRNode(source)
+		if err != nil {
+			return err
+		}
+		tf := SetScalarNodeFunc(envVarValueFrom, envVarSrc, path...)
+		return tf(obj)
+	}
+}
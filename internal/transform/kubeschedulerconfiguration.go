@@ -1,6 +1,11 @@
 package transform
 
 import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
 	"github.com/darkowlzz/operator-toolkit/declarative/transform"
 	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
 )
@@ -8,8 +13,30 @@ import (
 const (
 	leaderElection    = "leaderElection"
 	resourceNamespace = "resourceNamespace"
+	extenders         = "extenders"
+	urlPrefix         = "urlPrefix"
+	enableHTTPS       = "enableHTTPS"
+
+	profiles                 = "profiles"
+	schedulerNameField       = "schedulerName"
+	plugins                  = "plugins"
+	percentageOfNodesToScore = "percentageOfNodesToScore"
 )
 
+// KubeSchedulerPlugin is a scheduling framework plugin in a
+// KubeSchedulerConfiguration profile.
+type KubeSchedulerPlugin struct {
+	Name   string `json:"name"`
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// KubeSchedulerPluginSet contains the enabled and disabled plugins of an
+// extension point in a KubeSchedulerConfiguration profile.
+type KubeSchedulerPluginSet struct {
+	Enabled  []KubeSchedulerPlugin `json:"enabled,omitempty"`
+	Disabled []KubeSchedulerPlugin `json:"disabled,omitempty"`
+}
+
 // SetKubeSchedulerLeaderElectionRNamespaceFunc sets the leader election
 // resource namespace in a KubeSchedulerConfiguration.
 func SetKubeSchedulerLeaderElectionRNamespaceFunc(namespace string) transform.TransformFunc {
@@ -25,3 +52,93 @@ func SetKubeSchedulerLeaderElectionRNamespaceFunc(namespace string) transform.Tr
 		)
 	}
 }
+
+// SetKubeSchedulerExtenderURLPrefixFunc sets the host and port of the
+// extenders urlPrefix in a KubeSchedulerConfiguration, retaining the URL path.
+// The URL scheme and enableHTTPS are both set from the https flag, to keep
+// them in agreement.
+func SetKubeSchedulerExtenderURLPrefixFunc(host string, port int, https bool) transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		extenderList, err := obj.Pipe(kyaml.Lookup(extenders))
+		if err != nil {
+			return err
+		}
+		// Nothing to do if there are no extenders.
+		if extenderList == nil {
+			return nil
+		}
+
+		elements, err := extenderList.Elements()
+		if err != nil {
+			return err
+		}
+		for _, extender := range elements {
+			prefix, err := extender.Pipe(kyaml.Get(urlPrefix))
+			if err != nil {
+				return err
+			}
+			if prefix == nil {
+				return fmt.Errorf("extender %s not found", urlPrefix)
+			}
+
+			u, err := url.Parse(kyaml.GetValue(prefix))
+			if err != nil {
+				return fmt.Errorf("failed to parse extender %s: %w", urlPrefix, err)
+			}
+			u.Scheme = "http"
+			if https {
+				u.Scheme = "https"
+			}
+			u.Host = net.JoinHostPort(host, strconv.Itoa(port))
+
+			if err := extender.PipeE(kyaml.SetField(urlPrefix, kyaml.NewScalarRNode(u.String()))); err != nil {
+				return err
+			}
+			if err := extender.PipeE(kyaml.SetField(enableHTTPS, kyaml.NewScalarRNode(strconv.FormatBool(https)))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// RemoveKubeSchedulerExtendersFunc removes all the extenders from a
+// KubeSchedulerConfiguration.
+func RemoveKubeSchedulerExtendersFunc() transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		return obj.PipeE(kyaml.Clear(extenders))
+	}
+}
+
+// SetKubeSchedulerPercentageOfNodesToScoreFunc sets the percentage of nodes
+// to score in a KubeSchedulerConfiguration.
+func SetKubeSchedulerPercentageOfNodesToScoreFunc(percentage int32) transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		return obj.PipeE(kyaml.SetField(percentageOfNodesToScore, kyaml.NewScalarRNode(strconv.Itoa(int(percentage)))))
+	}
+}
+
+// SetKubeSchedulerProfilePluginsFunc sets the plugins of an extension point in
+// the profile of the given scheduler in a KubeSchedulerConfiguration. Any
+// existing plugins of the extension point are replaced.
+func SetKubeSchedulerProfilePluginsFunc(schedulerName string, extensionPoint string, pluginSet KubeSchedulerPluginSet) transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		profile, err := obj.Pipe(kyaml.Lookup(profiles, fmt.Sprintf("[%s=%s]", schedulerNameField, schedulerName)))
+		if err != nil {
+			return err
+		}
+		if profile == nil {
+			return fmt.Errorf("profile for scheduler %q not found", schedulerName)
+		}
+
+		pluginSetNode, err := goToRNode(pluginSet)
+		if err != nil {
+			return err
+		}
+
+		return profile.PipeE(
+			kyaml.LookupCreate(kyaml.MappingNode, plugins),
+			kyaml.SetField(extensionPoint, pluginSetNode),
+		)
+	}
+}
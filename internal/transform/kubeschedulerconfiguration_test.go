@@ -103,3 +103,295 @@ leaderElection:
 		})
 	}
 }
+
+func TestSetKubeSchedulerExtenderURLPrefixFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+extenders:
+  - urlPrefix: "http://foo:5705/v2/k8s/scheduler"
+    filterVerb: filter
+    enableHTTPS: false
+`)
+	assert.Nil(t, err)
+
+	testObjNoExtenders, err := kyaml.Parse(`
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+`)
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name                string
+		obj                 *kyaml.RNode
+		host                string
+		port                int
+		https               bool
+		wantSchedulerConfig string
+	}{
+		{
+			name: "namespace qualified host and port",
+			obj:  testObj,
+			host: "bar.namespaceA.svc",
+			port: 8080,
+			wantSchedulerConfig: `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+extenders:
+  - urlPrefix: "http://bar.namespaceA.svc:8080/v2/k8s/scheduler"
+    filterVerb: filter
+    enableHTTPS: false
+`,
+		},
+		{
+			name:  "https",
+			obj:   testObj,
+			host:  "foo.namespaceB.svc",
+			port:  5705,
+			https: true,
+			wantSchedulerConfig: `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+extenders:
+  - urlPrefix: "https://foo.namespaceB.svc:5705/v2/k8s/scheduler"
+    filterVerb: filter
+    enableHTTPS: true
+`,
+		},
+		{
+			name: "no extenders",
+			obj:  testObjNoExtenders,
+			host: "foo.namespaceC.svc",
+			port: 5705,
+			wantSchedulerConfig: `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			obj := tc.obj.Copy()
+
+			tf := SetKubeSchedulerExtenderURLPrefixFunc(tc.host, tc.port, tc.https)
+			err = tf(obj)
+			assert.Nil(t, err)
+
+			// Check the result.
+			gotStr, err := obj.String()
+			assert.Nil(t, err)
+			assert.Equal(t, strings.TrimSpace(tc.wantSchedulerConfig), strings.TrimSpace(gotStr))
+		})
+	}
+}
+
+func TestRemoveKubeSchedulerExtendersFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+extenders:
+  - urlPrefix: "http://foo:5705/v2/k8s/scheduler"
+    filterVerb: filter
+leaderElection:
+  leaderElect: true
+`)
+	assert.Nil(t, err)
+
+	wantSchedulerConfig := `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+leaderElection:
+  leaderElect: true
+`
+
+	tf := RemoveKubeSchedulerExtendersFunc()
+	err = tf(testObj)
+	assert.Nil(t, err)
+
+	// Check the result.
+	gotStr, err := testObj.String()
+	assert.Nil(t, err)
+	assert.Equal(t, strings.TrimSpace(wantSchedulerConfig), strings.TrimSpace(gotStr))
+}
+
+func TestSetKubeSchedulerPercentageOfNodesToScoreFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+`)
+	assert.Nil(t, err)
+
+	testObjWithPercentage, err := kyaml.Parse(`
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+percentageOfNodesToScore: 10
+profiles:
+  - schedulerName: foo-scheduler
+`)
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name                string
+		obj                 *kyaml.RNode
+		percentage          int32
+		wantSchedulerConfig string
+	}{
+		{
+			name:       "percentage not set",
+			obj:        testObj,
+			percentage: 50,
+			wantSchedulerConfig: `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+percentageOfNodesToScore: 50
+`,
+		},
+		{
+			name:       "percentage set",
+			obj:        testObjWithPercentage,
+			percentage: 100,
+			wantSchedulerConfig: `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+percentageOfNodesToScore: 100
+profiles:
+  - schedulerName: foo-scheduler
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			obj := tc.obj.Copy()
+
+			tf := SetKubeSchedulerPercentageOfNodesToScoreFunc(tc.percentage)
+			err = tf(obj)
+			assert.Nil(t, err)
+
+			// Check the result.
+			gotStr, err := obj.String()
+			assert.Nil(t, err)
+			assert.Equal(t, strings.TrimSpace(tc.wantSchedulerConfig), strings.TrimSpace(gotStr))
+		})
+	}
+}
+
+func TestSetKubeSchedulerProfilePluginsFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+  - schedulerName: bar-scheduler
+    plugins:
+      score:
+        disabled:
+          - name: '*'
+`)
+	assert.Nil(t, err)
+
+	weight := int32(5)
+
+	cases := []struct {
+		name                string
+		schedulerName       string
+		extensionPoint      string
+		pluginSet           KubeSchedulerPluginSet
+		wantErr             bool
+		wantSchedulerConfig string
+	}{
+		{
+			name:           "no plugins",
+			schedulerName:  "foo-scheduler",
+			extensionPoint: "score",
+			pluginSet: KubeSchedulerPluginSet{
+				Enabled:  []KubeSchedulerPlugin{{Name: "NodeResourcesBalancedAllocation", Weight: &weight}},
+				Disabled: []KubeSchedulerPlugin{{Name: "ImageLocality"}},
+			},
+			wantSchedulerConfig: `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+    plugins:
+      score:
+        disabled:
+          - name: ImageLocality
+        enabled:
+          - name: NodeResourcesBalancedAllocation
+            weight: 5
+  - schedulerName: bar-scheduler
+    plugins:
+      score:
+        disabled:
+          - name: '*'
+`,
+		},
+		{
+			name:           "replace existing plugins",
+			schedulerName:  "bar-scheduler",
+			extensionPoint: "score",
+			pluginSet: KubeSchedulerPluginSet{
+				Disabled: []KubeSchedulerPlugin{{Name: "ImageLocality"}},
+			},
+			wantSchedulerConfig: `
+apiVersion: kubescheduler.config.k8s.io/v1beta1
+kind: KubeSchedulerConfiguration
+profiles:
+  - schedulerName: foo-scheduler
+  - schedulerName: bar-scheduler
+    plugins:
+      score:
+        disabled:
+          - name: ImageLocality
+`,
+		},
+		{
+			name:           "unknown scheduler",
+			schedulerName:  "baz-scheduler",
+			extensionPoint: "filter",
+			wantErr:        true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			obj := testObj.Copy()
+
+			tf := SetKubeSchedulerProfilePluginsFunc(tc.schedulerName, tc.extensionPoint, tc.pluginSet)
+			err = tf(obj)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+
+			// Check the result.
+			gotStr, err := obj.String()
+			assert.Nil(t, err)
+			assert.Equal(t, strings.TrimSpace(tc.wantSchedulerConfig), strings.TrimSpace(gotStr))
+		})
+	}
+}
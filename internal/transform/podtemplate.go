@@ -2,6 +2,7 @@ package transform
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/darkowlzz/operator-toolkit/declarative/transform"
 	corev1 "k8s.io/api/core/v1"
@@ -21,6 +22,49 @@ func AppendPodTemplateContainerArgsFunc(container string, vals []string) transfo
 	return AppendSequenceNodeFunc(kyaml.NewListRNode(vals...), path...)
 }
 
+// AppendPodTemplateInitContainerArgsFunc adds a list of args in a given init
+// container in a PodTemplate.
+func AppendPodTemplateInitContainerArgsFunc(container string, vals []string) transform.TransformFunc {
+	path := getPodTemplateContainerArgsPath(containerTypeInit, container)
+	return AppendSequenceNodeFunc(kyaml.NewListRNode(vals...), path...)
+}
+
+// SetPodTemplateContainerArgFunc sets the value of a flag arg in a given
+// container in a PodTemplate. An existing "<flag>=<value>" arg is replaced,
+// else the arg is appended.
+func SetPodTemplateContainerArgFunc(container, flag, value string) transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		path := getPodTemplateContainerArgsPath(containerTypeMain, container)
+		args, err := obj.Pipe(kyaml.LookupCreate(kyaml.SequenceNode, path...))
+		if err != nil {
+			return err
+		}
+		if args == nil {
+			return fmt.Errorf("container(%s): args not found", container)
+		}
+
+		arg := fmt.Sprintf("%s=%s", flag, value)
+		for _, node := range args.Content() {
+			if node.Value == flag || strings.HasPrefix(node.Value, flag+"=") {
+				node.Value = arg
+				return nil
+			}
+		}
+		return args.PipeE(kyaml.Append(kyaml.NewScalarRNode(arg).YNode()))
+	}
+}
+
+// RemovePodTemplateContainerFunc removes a given container from a
+// PodTemplate.
+func RemovePodTemplateContainerFunc(container string) transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		return obj.PipeE(
+			kyaml.Lookup("spec", "template", "spec", containerTypeMain),
+			kyaml.ElementSetter{Keys: []string{"name"}, Values: []string{container}},
+		)
+	}
+}
+
 // getPodTemplateEnvVarPath constructs path to an env var in a PodTemplate.
 func getPodTemplateEnvVarPath(containerType, container, key string) []string {
 	containerSelector := fmt.Sprintf("[name=%s]", container)
@@ -49,6 +93,13 @@ func SetPodTemplateContainerEnvVarValueFromFieldFunc(container, key, fieldPath s
 	return SetEnvVarValueFromFieldFunc(fieldPath, path...)
 }
 
+// SetPodTemplateContainerEnvVarValueFromFunc sets a valueFrom env var with the
+// given env var source for a container.
+func SetPodTemplateContainerEnvVarValueFromFunc(container, key string, source corev1.EnvVarSource) transform.TransformFunc {
+	path := getPodTemplateEnvVarPath(containerTypeMain, container, key)
+	return SetEnvVarValueFromFunc(source, path...)
+}
+
 // SetPodTemplateInitContainerEnvVarStringFunc sets a string value env var for an
 // init container.
 func SetPodTemplateInitContainerEnvVarStringFunc(container, key, val string) transform.TransformFunc {
@@ -70,6 +121,13 @@ func SetPodTemplateInitContainerEnvVarValueFromFieldFunc(container, key, fieldPa
 	return SetEnvVarValueFromFieldFunc(fieldPath, path...)
 }
 
+// SetPodTemplateInitContainerEnvVarValueFromFunc sets a valueFrom env var with
+// the given env var source for an init container.
+func SetPodTemplateInitContainerEnvVarValueFromFunc(container, key string, source corev1.EnvVarSource) transform.TransformFunc {
+	path := getPodTemplateEnvVarPath(containerTypeInit, container, key)
+	return SetEnvVarValueFromFunc(source, path...)
+}
+
 // SetPodTemplateVolumeFunc sets a volume in a PodTemplate for the given name
 // and volume source.
 func SetPodTemplateVolumeFunc(volume string, volumeSource string, value *kyaml.RNode) transform.TransformFunc {
@@ -120,14 +178,53 @@ func SetPodTemplateSecretVolumeFunc(volume string, secretName string, keyToPaths
 	}
 }
 
-// SetPodTemplateVolumeMountFunc sets a volumeMount for a given container in a
+// SetPodTemplateVolumeSourceFunc sets a volume in a PodTemplate for any given
+// volume source.
+func SetPodTemplateVolumeSourceFunc(volume string, volumeSource corev1.VolumeSource) transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		// Construct the volume source RNode. The resulting mapping contains
+		// the volume source name as the field name, e.g. hostPath, emptyDir.
+		src, err := goToRNode(volumeSource)
+		if err != nil {
+			return err
+		}
+		fields, err := src.Fields()
+		if err != nil {
+			return err
+		}
+		if len(fields) != 1 {
+			return fmt.Errorf("volume(%s): exactly one volume source must be set, found %d", volume, len(fields))
+		}
+		tf := SetPodTemplateVolumeFunc(volume, fields[0], src.Field(fields[0]).Value)
+		return tf(obj)
+	}
+}
+
+// getPodTemplateVolumeMountPath constructs path to a volume mount in a
 // PodTemplate.
-func SetPodTemplateVolumeMountFunc(container, volName, mountPath string, mountPropagation corev1.MountPropagationMode) transform.TransformFunc {
-	// Create selectors and path.
+func getPodTemplateVolumeMountPath(containerType, container, volName string) []string {
 	containerSelector := fmt.Sprintf("[name=%s]", container)
 	volumeMountSelector := fmt.Sprintf("[name=%s]", volName)
-	path := []string{"spec", "template", "spec", "containers", containerSelector, "volumeMounts", volumeMountSelector}
+	return []string{"spec", "template", "spec", containerType, containerSelector, "volumeMounts", volumeMountSelector}
+}
+
+// SetPodTemplateVolumeMountFunc sets a volumeMount for a given container in a
+// PodTemplate.
+func SetPodTemplateVolumeMountFunc(container, volName, mountPath string, mountPropagation corev1.MountPropagationMode) transform.TransformFunc {
+	path := getPodTemplateVolumeMountPath(containerTypeMain, container, volName)
+	return setPodTemplateVolumeMountFunc(path, mountPath, mountPropagation)
+}
+
+// SetPodTemplateInitContainerVolumeMountFunc sets a volumeMount for a given
+// init container in a PodTemplate.
+func SetPodTemplateInitContainerVolumeMountFunc(container, volName, mountPath string, mountPropagation corev1.MountPropagationMode) transform.TransformFunc {
+	path := getPodTemplateVolumeMountPath(containerTypeInit, container, volName)
+	return setPodTemplateVolumeMountFunc(path, mountPath, mountPropagation)
+}
 
+// setPodTemplateVolumeMountFunc sets a volumeMount at the given volume mount
+// path.
+func setPodTemplateVolumeMountFunc(path []string, mountPath string, mountPropagation corev1.MountPropagationMode) transform.TransformFunc {
 	mountPathRNode := kyaml.NewScalarRNode(mountPath)
 
 	var mountPropagationRNode *kyaml.RNode = nil
@@ -138,6 +235,20 @@ func SetPodTemplateVolumeMountFunc(container, volName, mountPath string, mountPr
 	return SetVolumeMountFunc(mountPathRNode, mountPropagationRNode, path...)
 }
 
+// SetPodTemplateVolumeMountReadOnlyFunc marks a volumeMount of a given
+// container in a PodTemplate as read-only.
+func SetPodTemplateVolumeMountReadOnlyFunc(container, volName string) transform.TransformFunc {
+	path := getPodTemplateVolumeMountPath(containerTypeMain, container, volName)
+	return SetScalarNodeStringValueFunc(volMountReadOnly, "true", path...)
+}
+
+// SetPodTemplateInitContainerVolumeMountReadOnlyFunc marks a volumeMount of a
+// given init container in a PodTemplate as read-only.
+func SetPodTemplateInitContainerVolumeMountReadOnlyFunc(container, volName string) transform.TransformFunc {
+	path := getPodTemplateVolumeMountPath(containerTypeInit, container, volName)
+	return SetScalarNodeStringValueFunc(volMountReadOnly, "true", path...)
+}
+
 // SetPodTemplateContainerResourceFunc sets the resource requirements of a
 // container in a PodTemplate.
 func SetPodTemplateContainerResourceFunc(container string, resReq corev1.ResourceRequirements) transform.TransformFunc {
@@ -205,3 +316,44 @@ func SetPodTemplateNodeSelectorTermsFunc(nodeSelectors []corev1.NodeSelectorTerm
 		)
 	}
 }
+
+// SetPodTemplatePodAntiAffinityFunc sets the pod anti-affinity in a
+// PodTemplate. Any other affinity configuration in the PodTemplate, like node
+// affinity, is preserved.
+func SetPodTemplatePodAntiAffinityFunc(antiAffinity corev1.PodAntiAffinity) transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		// Construct path and RNode.
+		path := []string{"spec", "template", "spec", "affinity"}
+		aa, err := goToRNode(antiAffinity)
+		if err != nil {
+			return err
+		}
+
+		return obj.PipeE(
+			kyaml.LookupCreate(kyaml.MappingNode, path...),
+			kyaml.SetField(podAntiAffinityField, aa),
+		)
+	}
+}
+
+// SetPodTemplateTopologySpreadConstraintsFunc sets the topology spread
+// constraints in a PodTemplate.
+func SetPodTemplateTopologySpreadConstraintsFunc(constraints []corev1.TopologySpreadConstraint) transform.TransformFunc {
+	return func(obj *kyaml.RNode) error {
+		// Construct path and RNode.
+		path := []string{"spec", "template", "spec"}
+		tsc, err := goToRNode(constraints)
+		if err != nil {
+			return err
+		}
+
+		tf := SetScalarNodeFunc(topologySpreadConstraintsField, tsc, path...)
+		return tf(obj)
+	}
+}
+
+// SetPodTemplatePriorityClassNameFunc sets the priority class name in a
+// PodTemplate.
+func SetPodTemplatePriorityClassNameFunc(name string) transform.TransformFunc {
+	return SetScalarNodeStringValueFunc(priorityClassNameField, name, "spec", "template", "spec")
+}
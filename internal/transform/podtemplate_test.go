@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
@@ -228,6 +229,111 @@ fieldRef:
 	}
 }
 
+func TestSetPodTemplateEnvVarValueFromFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: some-daemonset
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: myinit
+        image: some-init:v1.0.0
+      containers:
+      - name: myapp
+        image: some-app:v1.1.1
+        env:
+        - name: FOO
+          value: bar
+`)
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name      string
+		container string
+		init      bool
+		key       string
+		source    corev1.EnvVarSource
+		wantVal   string
+	}{
+		{
+			name:      "configmap key ref",
+			container: "myapp",
+			key:       "SOME_CONFIG",
+			source: corev1.EnvVarSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "some-config"},
+					Key:                  "foo",
+				},
+			},
+			wantVal: `
+configMapKeyRef:
+  key: foo
+  name: some-config`,
+		},
+		{
+			name:      "overwrite value with resource field ref",
+			container: "myapp",
+			key:       "FOO",
+			source: corev1.EnvVarSource{
+				ResourceFieldRef: &corev1.ResourceFieldSelector{
+					Resource: "limits.memory",
+				},
+			},
+			wantVal: `
+resourceFieldRef:
+  divisor: "0"
+  resource: limits.memory`,
+		},
+		{
+			name:      "init container secret key ref",
+			container: "myinit",
+			init:      true,
+			key:       "SOME_SECRET",
+			source: corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "some-secret"},
+					Key:                  "password",
+				},
+			},
+			wantVal: `
+secretKeyRef:
+  key: password
+  name: some-secret`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// Make a copy of the object.
+			obj := testObj.Copy()
+
+			// Transform.
+			containerType := "containers"
+			tf := SetPodTemplateContainerEnvVarValueFromFunc(tc.container, tc.key, tc.source)
+			if tc.init {
+				containerType = "initContainers"
+				tf = SetPodTemplateInitContainerEnvVarValueFromFunc(tc.container, tc.key, tc.source)
+			}
+			err = tf(obj)
+			assert.Nil(t, err)
+
+			// Query and check the value.
+			containerSelector := fmt.Sprintf("[name=%s]", tc.container)
+			envVarSelector := fmt.Sprintf("[name=%s]", tc.key)
+			val, err := obj.Pipe(kyaml.Lookup("spec", "template", "spec", containerType, containerSelector, "env", envVarSelector, "valueFrom"))
+			assert.Nil(t, err)
+
+			str, err := val.String()
+			assert.Nil(t, err)
+			assert.Equal(t, strings.TrimSpace(tc.wantVal), strings.TrimSpace(str))
+		})
+	}
+}
+
 func TestSetPodTemplateHostPathVolumeFunc(t *testing.T) {
 	testObj, err := kyaml.Parse(`
 apiVersion: apps/v1
@@ -504,6 +610,96 @@ secret:
 	}
 }
 
+func TestSetPodTemplateVolumeSourceFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: some-daemonset
+spec:
+  template:
+    spec:
+      containers:
+      - name: someotherapp
+        image: some-image:v1.2.3
+      volumes:
+      - name: foo
+        hostPath:
+          path: /var/lib/
+          type: Directory
+`)
+	assert.Nil(t, err)
+
+	sizeLimit := resource.MustParse("1Gi")
+
+	cases := []struct {
+		name         string
+		volume       string
+		volumeSource corev1.VolumeSource
+		wantErr      bool
+		wantVal      string
+	}{
+		{
+			name:   "new emptydir volume",
+			volume: "scratch",
+			volumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					Medium:    corev1.StorageMediumMemory,
+					SizeLimit: &sizeLimit,
+				},
+			},
+			wantVal: `
+name: scratch
+emptyDir:
+  medium: Memory
+  sizeLimit: 1Gi`,
+		},
+		{
+			name:   "new configmap volume",
+			volume: "bar",
+			volumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "some-config"},
+				},
+			},
+			wantVal: `
+name: bar
+configMap:
+  name: some-config`,
+		},
+		{
+			name:         "no volume source",
+			volume:       "empty",
+			volumeSource: corev1.VolumeSource{},
+			wantErr:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			obj := testObj.Copy()
+
+			// Transform.
+			tf := SetPodTemplateVolumeSourceFunc(tc.volume, tc.volumeSource)
+			err = tf(obj)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+
+			volumeSelector := fmt.Sprintf("[name=%s]", tc.volume)
+			val, err := obj.Pipe(kyaml.Lookup("spec", "template", "spec", "volumes", volumeSelector))
+			assert.Nil(t, err)
+
+			str, err := val.String()
+			assert.Nil(t, err)
+			assert.Equal(t, strings.TrimSpace(tc.wantVal), strings.TrimSpace(str))
+		})
+	}
+}
+
 func TestSetPodTemplateVolumeMountFunc(t *testing.T) {
 	testObj, err := kyaml.Parse(`
 apiVersion: apps/v1
@@ -1013,3 +1209,396 @@ spec:
 		})
 	}
 }
+
+func TestSetPodTemplateContainerArgFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: some-app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - args:
+        - server
+        - --registration-path=/var/lib/foo
+        image: some-image:v1.0.0
+        name: myapp
+      - name: my-other-app
+        image: some-other-image:v2.0.0
+`)
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name      string
+		container string
+		flag      string
+		value     string
+		want      []string
+	}{
+		{
+			name:      "replace existing arg",
+			container: "myapp",
+			flag:      "--registration-path",
+			value:     "/opt/kubelet/foo",
+			want:      []string{"server", "--registration-path=/opt/kubelet/foo"},
+		},
+		{
+			name:      "append arg",
+			container: "myapp",
+			flag:      "--timeout",
+			value:     "30s",
+			want:      []string{"server", "--registration-path=/var/lib/foo", "--timeout=30s"},
+		},
+		{
+			name:      "add arg to container without args",
+			container: "my-other-app",
+			flag:      "--timeout",
+			value:     "30s",
+			want:      []string{"--timeout=30s"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// Make a copy of the object.
+			obj := testObj.Copy()
+
+			// Transform.
+			tf := SetPodTemplateContainerArgFunc(tc.container, tc.flag, tc.value)
+			err = tf(obj)
+			assert.Nil(t, err)
+
+			// Query and check value.
+			containerSelector := fmt.Sprintf("[name=%s]", tc.container)
+			val, err := obj.Pipe(kyaml.Lookup("spec", "template", "spec", "containers", containerSelector, "args"))
+			assert.Nil(t, err)
+
+			str, err := val.String()
+			assert.Nil(t, err)
+
+			// Convert want list to RNode string value.
+			wantRNode := kyaml.NewListRNode(tc.want...)
+			wantStr, err := wantRNode.String()
+			assert.Nil(t, err)
+
+			assert.Equal(t, wantStr, str)
+		})
+	}
+}
+
+func TestRemovePodTemplateContainerFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: some-app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - image: some-image:v1.0.0
+        name: myapp
+      - name: my-other-app
+        image: some-other-image:v2.0.0
+`)
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name      string
+		container string
+		want      []string
+	}{
+		{
+			name:      "remove container",
+			container: "myapp",
+			want:      []string{"my-other-app"},
+		},
+		{
+			name:      "unknown container",
+			container: "foo",
+			want:      []string{"myapp", "my-other-app"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// Make a copy of the object.
+			obj := testObj.Copy()
+
+			// Transform.
+			tf := RemovePodTemplateContainerFunc(tc.container)
+			err = tf(obj)
+			assert.Nil(t, err)
+
+			// Query and check the container names.
+			containers, err := obj.Pipe(kyaml.Lookup("spec", "template", "spec", "containers"))
+			assert.Nil(t, err)
+			names, err := containers.ElementValues("name")
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, names)
+		})
+	}
+}
+
+func TestSetPodTemplatePodAntiAffinityFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: some-deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: someapp
+        image: someapp:v1.5
+`)
+	assert.Nil(t, err)
+
+	testObjWithAffinity, err := kyaml.Parse(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: some-deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: someapp
+        image: someapp:v1.5
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+              - matchExpressions:
+                  - key: somekey
+                    operator: In
+                    values:
+                    - someval
+        podAntiAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            - labelSelector:
+                matchLabels:
+                  app: foo
+              topologyKey: kubernetes.io/hostname
+`)
+	assert.Nil(t, err)
+
+	antiAffinity := corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+			{
+				Weight: 100,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"app": "someapp"},
+					},
+					TopologyKey: "kubernetes.io/hostname",
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		name         string
+		object       *kyaml.RNode
+		antiAffinity corev1.PodAntiAffinity
+		wantAffinity string
+	}{
+		{
+			name:         "add anti-affinity",
+			object:       testObj,
+			antiAffinity: antiAffinity,
+			wantAffinity: `
+podAntiAffinity:
+  preferredDuringSchedulingIgnoredDuringExecution:
+    - podAffinityTerm:
+        labelSelector:
+          matchLabels:
+            app: someapp
+        topologyKey: kubernetes.io/hostname
+      weight: 100`,
+		},
+		{
+			name:         "overwrite anti-affinity and keep node affinity",
+			object:       testObjWithAffinity,
+			antiAffinity: antiAffinity,
+			wantAffinity: `
+nodeAffinity:
+  requiredDuringSchedulingIgnoredDuringExecution:
+    nodeSelectorTerms:
+      - matchExpressions:
+          - key: somekey
+            operator: In
+            values:
+              - someval
+podAntiAffinity:
+  preferredDuringSchedulingIgnoredDuringExecution:
+    - podAffinityTerm:
+        labelSelector:
+          matchLabels:
+            app: someapp
+        topologyKey: kubernetes.io/hostname
+      weight: 100`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// Make a copy of the object.
+			obj := tc.object.Copy()
+
+			tf := SetPodTemplatePodAntiAffinityFunc(tc.antiAffinity)
+			err = tf(obj)
+			assert.Nil(t, err)
+
+			// Query and check the result.
+			gotAffinity, err := obj.Pipe(kyaml.Lookup("spec", "template", "spec", "affinity"))
+			assert.Nil(t, err)
+			gotStr, err := gotAffinity.String()
+			assert.Nil(t, err)
+			assert.Equal(t, strings.TrimSpace(tc.wantAffinity), strings.TrimSpace(gotStr))
+		})
+	}
+}
+
+func TestSetPodTemplateTopologySpreadConstraintsFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: some-deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: someapp
+        image: someapp:v1.5
+`)
+	assert.Nil(t, err)
+
+	testObjWithConstraints, err := kyaml.Parse(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: some-deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: someapp
+        image: someapp:v1.5
+      topologySpreadConstraints:
+        - maxSkew: 2
+          topologyKey: some-key
+          whenUnsatisfiable: DoNotSchedule
+`)
+	assert.Nil(t, err)
+
+	constraints := []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "someapp"},
+			},
+		},
+	}
+
+	cases := []struct {
+		name            string
+		object          *kyaml.RNode
+		constraints     []corev1.TopologySpreadConstraint
+		wantConstraints string
+	}{
+		{
+			name:        "add constraints",
+			object:      testObj,
+			constraints: constraints,
+			wantConstraints: `
+- labelSelector:
+    matchLabels:
+      app: someapp
+  maxSkew: 1
+  topologyKey: topology.kubernetes.io/zone
+  whenUnsatisfiable: ScheduleAnyway`,
+		},
+		{
+			name:        "overwrite constraints",
+			object:      testObjWithConstraints,
+			constraints: constraints,
+			wantConstraints: `
+- labelSelector:
+    matchLabels:
+      app: someapp
+  maxSkew: 1
+  topologyKey: topology.kubernetes.io/zone
+  whenUnsatisfiable: ScheduleAnyway`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// Make a copy of the object.
+			obj := tc.object.Copy()
+
+			tf := SetPodTemplateTopologySpreadConstraintsFunc(tc.constraints)
+			err = tf(obj)
+			assert.Nil(t, err)
+
+			// Query and check the result.
+			gotConstraints, err := obj.Pipe(kyaml.Lookup("spec", "template", "spec", "topologySpreadConstraints"))
+			assert.Nil(t, err)
+			gotStr, err := gotConstraints.String()
+			assert.Nil(t, err)
+			assert.Equal(t, strings.TrimSpace(tc.wantConstraints), strings.TrimSpace(gotStr))
+		})
+	}
+}
+
+func TestSetPodTemplatePriorityClassNameFunc(t *testing.T) {
+	testObj, err := kyaml.Parse(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: some-app
+spec:
+  template:
+    spec:
+      containers:
+      - image: some-image:v1.0.0
+        name: myapp
+      priorityClassName: system-cluster-critical
+`)
+	assert.Nil(t, err)
+
+	wantObj := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: some-app
+spec:
+  template:
+    spec:
+      containers:
+        - image: some-image:v1.0.0
+          name: myapp
+      priorityClassName: storageos
+`
+
+	tf := SetPodTemplatePriorityClassNameFunc("storageos")
+	err = tf(testObj)
+	assert.Nil(t, err)
+
+	gotStr, err := testObj.String()
+	assert.Nil(t, err)
+	assert.Equal(t, strings.TrimSpace(wantObj), strings.TrimSpace(gotStr))
+}
@@ -1,20 +1,26 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
-	"github.com/darkowlzz/operator-toolkit/telemetry/export"
 	"github.com/darkowlzz/operator-toolkit/webhook/cert"
 	"go.uber.org/zap/zapcore"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -25,12 +31,26 @@ import (
 	storageoscomv1 "github.com/storageos/operator/apis/v1"
 	"github.com/storageos/operator/controllers"
 	whctrlr "github.com/storageos/operator/controllers/webhook"
+	"github.com/storageos/operator/internal/distro"
+	"github.com/storageos/operator/internal/features"
+	"github.com/storageos/operator/internal/health"
+	"github.com/storageos/operator/internal/image"
+	"github.com/storageos/operator/internal/metrics"
+	"github.com/storageos/operator/internal/tracing"
 	// +kubebuilder:scaffold:imports
 )
 
 // podNamespace is the operator's pod namespace environment variable.
 const podNamespace = "POD_NAMESPACE"
 
+// relatedImageEnvVarPrefix is the prefix of the environment variables of the
+// operator default images.
+const relatedImageEnvVarPrefix = "RELATED_IMAGE_"
+
+// reconcileTimeout is the duration after which an in-flight reconcile is
+// considered wedged, failing the liveness check.
+const reconcileTimeout = 10 * time.Minute
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -65,14 +85,6 @@ func main() {
 	}
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts), zap.StacktraceLevel(zapcore.PanicLevel), encoderOpts))
 
-	// Setup telemetry.
-	telemetryShutdown, err := export.InstallJaegerExporter("storageos-operator")
-	if err != nil {
-		setupLog.Error(err, "unable to setup telemetry exporter")
-		os.Exit(1)
-	}
-	defer telemetryShutdown()
-
 	// Load controller manager configuration and create manager options from
 	// it.
 	ctrlConfig := configstorageoscomv1.OperatorConfig{}
@@ -86,6 +98,44 @@ func main() {
 		}
 	}
 
+	// Read the configmaps directly from the API server. Only the metadata of
+	// the configmaps is cached, to watch the node configurations.
+	options.ClientDisableCacheFor = append(options.ClientDisableCacheFor, &corev1.ConfigMap{})
+
+	// Validate the operator configuration and set the feature gates.
+	if err := ctrlConfig.Validate(); err != nil {
+		setupLog.Error(err, "invalid operator configuration")
+		os.Exit(1)
+	}
+	if err := features.Set(ctrlConfig.FeatureGates); err != nil {
+		setupLog.Error(err, "invalid feature gates")
+		os.Exit(1)
+	}
+
+	// Override the registry of the default images.
+	overrideRelatedImagesRegistry(ctrlConfig.ImageRegistry)
+
+	setupLog.Info("loaded operator configuration",
+		"executionStrategy", ctrlConfig.GetExecutionStrategy(),
+		"channel", ctrlConfig.GetChannel(),
+		"watchNamespaces", ctrlConfig.WatchNamespaces,
+		"imageRegistry", ctrlConfig.ImageRegistry,
+		"featureGates", features.All(),
+	)
+	metrics.SetOperatorConfig(string(ctrlConfig.GetExecutionStrategy()), ctrlConfig.GetChannel(), ctrlConfig.WatchNamespaces, ctrlConfig.ImageRegistry, features.All())
+
+	// Setup telemetry with the configured tracing exporter.
+	tracingShutdown, err := tracing.Setup(context.Background(), "storageos-operator", ctrlConfig.Tracing)
+	if err != nil {
+		setupLog.Error(err, "unable to setup telemetry exporter")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := tracingShutdown(); err != nil {
+			setupLog.Error(err, "failed to shutdown telemetry exporter")
+		}
+	}()
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -117,6 +167,7 @@ func main() {
 		Client:                      cli,
 		SecretRef:                   &types.NamespacedName{Name: ctrlConfig.WebhookSecretRef, Namespace: currentNS},
 		ValidatingWebhookConfigRefs: []types.NamespacedName{{Name: ctrlConfig.ValidatingWebhookConfigRef}},
+		MutatingWebhookConfigRefs:   []types.NamespacedName{{Name: ctrlConfig.MutatingWebhookConfigRef}},
 	}
 	// Create certificate manager without manager to start the provisioning
 	// immediately.
@@ -133,7 +184,37 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = controllers.NewStorageOSClusterReconciler(mgr).
+	// Report the expiry of the webhook serving certificate.
+	if err := metrics.RegisterWebhookCertExpiry(getWebhookCertPath(mgr)); err != nil {
+		setupLog.Error(err, "unable to register webhook certificate metrics")
+		os.Exit(1)
+	}
+
+	// Discover the kubernetes version to render version specific resources.
+	dc, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "failed to create discovery client")
+		os.Exit(1)
+	}
+	serverVersion, err := dc.ServerVersion()
+	if err != nil {
+		setupLog.Error(err, "failed to get kubernetes version")
+		os.Exit(1)
+	}
+	kubeVersion, err := version.ParseGeneric(serverVersion.GitVersion)
+	if err != nil {
+		setupLog.Error(err, "failed to parse kubernetes version", "version", serverVersion.GitVersion)
+		os.Exit(1)
+	}
+	setupLog.Info("discovered kubernetes version", "version", kubeVersion.String())
+
+	// Detect the kubernetes distribution to apply the distribution defaults to
+	// the clusters that don't specify one.
+	k8sDistro := detectDistro(dc, cli, serverVersion.GitVersion)
+	setupLog.Info("detected kubernetes distribution", "distro", k8sDistro)
+
+	reconcileTracker := health.NewReconcileTracker(reconcileTimeout)
+	if err = controllers.NewStorageOSClusterReconciler(mgr, kubeVersion, k8sDistro, reconcileTracker, ctrlConfig).
 		SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller",
 			"controller", "StorageOSCluster")
@@ -141,7 +222,7 @@ func main() {
 	}
 
 	// Create and set up admission webhook controller.
-	clusterWh, err := whctrlr.NewStorageOSClusterWebhook(mgr.GetClient(), mgr.GetScheme())
+	clusterWh, err := whctrlr.NewStorageOSClusterWebhook(mgr.GetClient(), mgr.GetScheme(), ctrlConfig.WatchNamespaces)
 	if err != nil {
 		setupLog.Error(err, "unable to create admission webhook controller",
 			"controller", clusterWh.CtrlName)
@@ -153,15 +234,38 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Create and set up the pod scheduler mutating webhook controller.
+	podWh := whctrlr.NewPodSchedulerWebhook(mgr.GetClient(), mgr.GetAPIReader())
+	if err := podWh.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to setup webhook controller with manager",
+			"controller", podWh.CtrlName)
+		os.Exit(1)
+	}
+
 	// +kubebuilder:scaffold:builder
 
-	if err := mgr.AddHealthzCheck("health", healthz.Ping); err != nil {
+	// Liveness fails when the reconcile loop is wedged. Readiness requires a
+	// serving webhook server with a valid certificate, synced informers and,
+	// unless the LeaderReadiness feature is disabled, the leadership, to be
+	// able to reconcile.
+	if err := mgr.AddHealthzCheck("reconcile", reconcileTracker.Check); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("check", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
-		os.Exit(1)
+	webhookServer := mgr.GetWebhookServer()
+	readyChecks := map[string]healthz.Checker{
+		"webhook-tls":   health.WebhookTLSCheck(webhookServer.Host, webhookServer.Port),
+		"webhook-cert":  health.CertValidityCheck(getWebhookCertPath(mgr)),
+		"informer-sync": health.CacheSyncCheck(mgr.GetCache()),
+	}
+	if features.Enabled(features.LeaderReadiness) {
+		readyChecks["leader"] = health.LeaderCheck(mgr.Elected())
+	}
+	for name, check := range readyChecks {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up ready check", "check", name)
+			os.Exit(1)
+		}
 	}
 
 	setupLog.Info("starting manager")
@@ -170,3 +274,63 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// detectDistro detects the kubernetes distribution from the served API groups
+// and the labels of a node. Detection failures are logged and result in an
+// empty distribution.
+func detectDistro(dc discovery.DiscoveryInterface, cl client.Client, gitVersion string) string {
+	apiGroups := []string{}
+	groups, err := dc.ServerGroups()
+	if err != nil {
+		setupLog.Error(err, "failed to get API groups")
+	} else {
+		for _, g := range groups.Groups {
+			apiGroups = append(apiGroups, g.Name)
+		}
+	}
+
+	var nodeLabels map[string]string
+	nodes := &corev1.NodeList{}
+	if err := cl.List(context.Background(), nodes, client.Limit(1)); err != nil {
+		setupLog.Error(err, "failed to list nodes")
+	} else if len(nodes.Items) > 0 {
+		nodeLabels = nodes.Items[0].GetLabels()
+	}
+
+	return distro.Detect(gitVersion, apiGroups, nodeLabels)
+}
+
+// overrideRelatedImagesRegistry replaces the registry of the default images,
+// set in the related image environment variables, with the given registry.
+// The scheduler operand overrides the registry of the default kube-scheduler
+// image, derived from the kubernetes version.
+func overrideRelatedImagesRegistry(registry string) {
+	if registry == "" {
+		return
+	}
+	for _, env := range os.Environ() {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], relatedImageEnvVarPrefix) || kv[1] == "" {
+			continue
+		}
+		if err := os.Setenv(kv[0], image.WithRegistry(kv[1], registry)); err != nil {
+			setupLog.Error(err, "failed to override image registry", "env", kv[0])
+		}
+	}
+}
+
+// getWebhookCertPath returns the path of the webhook server certificate. The
+// webhook server defaults are used when the manager doesn't configure the
+// certificate location.
+func getWebhookCertPath(mgr ctrl.Manager) string {
+	server := mgr.GetWebhookServer()
+	certDir := server.CertDir
+	if certDir == "" {
+		certDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+	}
+	certName := server.CertName
+	if certName == "" {
+		certName = "tls.crt"
+	}
+	return filepath.Join(certDir, certName)
+}